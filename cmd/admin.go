@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bengissimo/tictactoe/pkg/game"
+)
+
+const usage = `usage: tictactoe [flags] [command]
+
+Without a command, tictactoe serves the API. The commands talk to a running
+server at -base-url, or else at -addr, with -admin-token:
+
+  export [file]  write a snapshot of all games to file, or to stdout
+  import file    load the games of a snapshot file, replacing games with the
+                 same IDs`
+
+// runCommand runs the admin command named by the first of args.
+func runCommand(config game.Config, args []string) error {
+	switch {
+	case args[0] == "export" && len(args) <= 2:
+		return exportGames(config, args[1:])
+	case args[0] == "import" && len(args) == 2:
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return importGames(config, f)
+	}
+	return fmt.Errorf("unknown command %q\n%s", strings.Join(args, " "), usage)
+}
+
+// exportGames writes the snapshot to the file named in args, which is only
+// created once the server has answered, or to stdout.
+func exportGames(config game.Config, args []string) error {
+	resp, err := adminRequest(config, "GET", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if len(args) == 0 {
+		_, err = io.Copy(os.Stdout, resp.Body)
+		return err
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func importGames(config game.Config, in io.Reader) error {
+	resp, err := adminRequest(config, "POST", in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	result := struct {
+		Imported int `json:"imported"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "imported %d games\n", result.Imported)
+	return nil
+}
+
+// adminRequest sends a request to the snapshot endpoint of the server and
+// turns problem responses into errors.
+func adminRequest(config game.Config, method string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, serverURL(config)+"/api/v1/admin/snapshot", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.AdminToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		problem := game.Problem{}
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Detail == "" {
+			return nil, fmt.Errorf("%s %s: %s", method, req.URL, resp.Status)
+		}
+		return nil, fmt.Errorf("%s %s: %s (%s)", method, req.URL, problem.Detail, problem.Code)
+	}
+	return resp, nil
+}
+
+// serverURL returns the URL of the server to send commands to: the base URL,
+// or the listen address with wildcard hosts replaced by the local host.
+func serverURL(config game.Config) string {
+	if config.BaseURL != "" {
+		return strings.TrimSuffix(config.BaseURL, "/")
+	}
+	host, port, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return "http://" + config.Addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -1,15 +1,82 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/bengissimo/tictactoe/pkg/game"
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	gs := game.NewStore()
+	config, err := game.LoadConfig(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	if err := gs.Router.Run("0.0.0.0:8080"); err != nil {
+	if len(config.Args) > 0 {
+		err = runCommand(config, config.Args)
+	} else {
+		err = run(config)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// run serves the API until SIGINT or SIGTERM, then gives open requests and
+// streams the shutdown timeout to finish and saves the games.
+func run(config game.Config) error {
+	gin.SetMode(config.GinMode)
+
+	repo, err := game.OpenRepository(config.Storage, config.DataDir)
+	if err != nil {
+		return err
+	}
+
+	gs := game.NewStore(game.WithRepository(repo), game.WithConfig(config))
+	if config.Snapshot != "" {
+		if err := gs.LoadSnapshot(config.Snapshot); err != nil {
+			return err
+		}
+	}
+
+	server := &http.Server{Addr: config.Addr, Handler: gs.Router}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	log.Println("shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout))
+	defer cancel()
+
+	if err := gs.Drain(ctx); err != nil {
+		log.Println("streams still open:", err)
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("requests still open:", err)
+	}
+	return gs.Flush()
+}
@@ -0,0 +1,58 @@
+package game
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin lets requests through that carry the configured admin token as
+// a bearer token. Admin endpoints are disabled while no token is configured.
+func (s *Store) requireAdmin(c *gin.Context) {
+	if s.adminToken == "" {
+		abortWithError(c, newAPIError(403, CODE_ADMIN_DISABLED, "Admin endpoints are disabled"))
+		return
+	}
+
+	header := c.GetHeader("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+		abortWithError(c, newAPIError(401, CODE_UNAUTHORIZED, "Invalid admin token"))
+		return
+	}
+}
+
+// ExportGames answers with a snapshot of every game, including the moves and
+// seat tokens, that ImportGames can load into this or another server.
+func (s *Store) ExportGames(c *gin.Context) {
+	body := &bytes.Buffer{}
+	if err := s.exportGames(body); err != nil {
+		abortWithError(c, storageError(err))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="tictactoe-snapshot.json"`)
+	c.Data(200, "application/json", body.Bytes())
+}
+
+// ImportGames stores the games of the snapshot in the request body, replacing
+// games with the same IDs. Nothing is stored unless the whole snapshot is
+// valid.
+func (s *Store) ImportGames(c *gin.Context) {
+	games, err := readSnapshot(c.Request.Body)
+	if err != nil {
+		e := newAPIError(400, CODE_INVALID_SNAPSHOT, "Invalid snapshot: "+err.Error())
+		abortWithError(c, e)
+		return
+	}
+
+	if err := s.importGames(games); err != nil {
+		abortWithError(c, storageError(err))
+		return
+	}
+
+	c.JSON(200, gin.H{"imported": len(games)})
+}
@@ -0,0 +1,102 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func adminConfig() Config {
+	config := DefaultConfig()
+	config.AdminToken = "secret"
+	return config
+}
+
+func callAdmin(router http.Handler, method, token, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/api/v1/admin/snapshot", bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestStore_AdminToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		token    string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "disabled", config: DefaultConfig(), token: "secret", wantCode: 403, wantErr: CODE_ADMIN_DISABLED},
+		{name: "missing token", config: adminConfig(), wantCode: 401, wantErr: CODE_UNAUTHORIZED},
+		{name: "wrong token", config: adminConfig(), token: "guess", wantCode: 401, wantErr: CODE_UNAUTHORIZED},
+		{name: "valid token", config: adminConfig(), token: "secret", wantCode: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(WithConfig(tt.config))
+			w := callAdmin(store.Router, "GET", tt.token, "")
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantErr != "" {
+				assert.Contains(t, w.Body.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore_ExportImport(t *testing.T) {
+	store := NewStore(WithConfig(adminConfig()))
+	pvc, _, _ := callCreateGame(store.Router, `{"board":"X--------","strategy":"greedy"}`)
+	pvp, _, _ := callCreateGame(store.Router, `{"board":"---------","mode":"pvp"}`)
+
+	w := callAdmin(store.Router, "GET", "secret", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	exported := w.Body.String()
+
+	// Snapshots are not bound by the body limit of the API.
+	config := adminConfig()
+	config.MaxBodyBytes = 64
+	other := NewStore(WithConfig(config))
+	w = callAdmin(other.Router, "POST", "secret", exported)
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"imported":2}`, w.Body.String())
+
+	game, err := other.Games.Get(pvc.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, pvc.Board, game.Board)
+	assert.Equal(t, pvc.Moves, game.Moves)
+	assert.Equal(t, STRATEGY_GREEDY, game.Strategy)
+	game, err = other.Games.Get(pvp.ID)
+	assert.NoError(t, err)
+	original, _ := store.Games.Get(pvp.ID)
+	assert.Equal(t, original.seats, game.seats)
+
+	w = callAdmin(other.Router, "GET", "secret", "")
+	s := snapshot{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(t, SNAPSHOT_VERSION, s.Version)
+	assert.Len(t, s.Games, 2)
+
+	invalid := strings.Replace(exported, `"board":"X`, `"board":"Z`, 1)
+	w = callAdmin(other.Router, "POST", "secret", invalid)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), CODE_INVALID_SNAPSHOT)
+
+	w = callAdmin(other.Router, "POST", "secret", snapshotOf(`"moves":[{"cell":0}]`))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), CODE_INVALID_SNAPSHOT)
+
+	w = callAdmin(other.Router, "POST", "secret", `{"version":2,"games":[]}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "unsupported snapshot version 2")
+}
@@ -0,0 +1,133 @@
+package game
+
+import "strings"
+
+// AnalysisRequest is the body of an analysis request. Size and WinLength
+// default as they do for new games. FirstPlayer is only needed to decide whose
+// turn it is when both symbols have been played equally often: without it, O
+// opened the game if it has one more mark than X, and X opened it otherwise.
+type AnalysisRequest struct {
+	Board       string `json:"board" binding:"required"`
+	Size        int    `json:"size"`
+	WinLength   int    `json:"win_length"`
+	FirstPlayer Symbol `json:"first_player"`
+}
+
+// Analysis describes an arbitrary board position. Turn and Value are only set
+// for legal positions: Turn while the game is running and Value as the status
+// the game ends with when both sides play perfectly from here. Boards too
+// large to search to the end report a draw unless a forced win was found.
+type Analysis struct {
+	Board       string `json:"board"`
+	Legal       bool   `json:"legal"`
+	Status      string `json:"status"`
+	Turn        string `json:"turn,omitempty"`
+	Value       string `json:"value,omitempty"`
+	WinningLine []int  `json:"winning_line,omitempty"`
+}
+
+func parseSymbol(s string) (byte, bool) {
+	switch strings.ToUpper(s) {
+	case string(SYMBOL_X):
+		return SYMBOL_X, true
+	case string(SYMBOL_O):
+		return SYMBOL_O, true
+	}
+	return 0, false
+}
+
+// analyze evaluates board, assuming first made the opening move, or the
+// player the marks on the board point to if first is 0.
+func analyze(board string, first byte, rules Rules) Analysis {
+	first = openingPlayer(board, first)
+	g := &Game{Board: board, Size: rules.Size, WinLength: rules.WinLength, Status: STATUS_RUNNING}
+	g.updateStatus()
+
+	analysis := Analysis{
+		Board:       board,
+		Legal:       legalPosition(board, first, rules),
+		Status:      g.Status,
+		WinningLine: g.WinningLine,
+	}
+	if !analysis.Legal {
+		return analysis
+	}
+
+	if g.Status != STATUS_RUNNING {
+		analysis.Value = g.Status
+		return analysis
+	}
+
+	turn := nextTurn(board, first)
+	analysis.Turn = string(turn)
+	analysis.Value = perfectPlay(board, turn, rules)
+	return analysis
+}
+
+// perfectPlay returns the status a running game on board ends with when both
+// sides play perfectly, turn moving next.
+func perfectPlay(board string, turn byte, rules Rules) string {
+	switch outcome(solve(board, turn, rules)) {
+	case OUTCOME_WIN:
+		return wonStatus(turn)
+	case OUTCOME_LOSS:
+		return wonStatus(opponent(turn))
+	}
+	return STATUS_DRAW
+}
+
+// nextTurn returns the symbol to move on board when first opened the game.
+func nextTurn(board string, first byte) byte {
+	if strings.Count(board, string(first)) > strings.Count(board, string(opponent(first))) {
+		return opponent(first)
+	}
+	return first
+}
+
+// openingPlayer returns first, or if it is 0, the player who must have opened
+// the game on board: O if it has one more mark than X, and X otherwise.
+func openingPlayer(board string, first byte) byte {
+	if first != 0 {
+		return first
+	}
+	if strings.Count(board, string(SYMBOL_O)) == strings.Count(board, string(SYMBOL_X))+1 {
+		return SYMBOL_O
+	}
+	return SYMBOL_X
+}
+
+// legalPosition reports whether board can be reached by alternating moves
+// starting with first, or with the player the marks point to if first is 0.
+// The player who moved first has played as many or one
+// more piece than the other, and a won game must have been won with the last
+// move: the winner moved last and one of their pieces completes every
+// winning line on the board.
+func legalPosition(board string, first byte, rules Rules) bool {
+	first = openingPlayer(board, first)
+	firstCount := strings.Count(board, string(first))
+	secondCount := strings.Count(board, string(opponent(first)))
+	if firstCount != secondCount && firstCount != secondCount+1 {
+		return false
+	}
+
+	winner := byte(0)
+	switch evaluate(board, rules) {
+	case STATUS_X_WON:
+		winner = SYMBOL_X
+	case STATUS_O_WON:
+		winner = SYMBOL_O
+	default:
+		return true
+	}
+
+	if winner != opponent(nextTurn(board, first)) {
+		return false
+	}
+
+	for i := 0; i < len(board); i++ {
+		if board[i] == winner && evaluate(replaceAtIndex(board, EMPTY, i), rules) == STATUS_RUNNING {
+			return true
+		}
+	}
+	return false
+}
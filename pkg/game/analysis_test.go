@@ -0,0 +1,180 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGame_legalPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		first    byte
+		expected bool
+	}{
+		{
+			name:     "empty board",
+			board:    "---------",
+			first:    SYMBOL_X,
+			expected: true,
+		},
+		{
+			name:     "O moved first",
+			board:    "O--------",
+			first:    SYMBOL_O,
+			expected: true,
+		},
+		{
+			name:     "O moved first by the count",
+			board:    "O--------",
+			first:    0,
+			expected: true,
+		},
+		{
+			name:     "O moved out of turn",
+			board:    "O--------",
+			first:    SYMBOL_X,
+			expected: false,
+		},
+		{
+			name:     "X moved first by the count",
+			board:    "X---O----",
+			first:    0,
+			expected: true,
+		},
+		{
+			name:     "too many X",
+			board:    "XX-O-----",
+			first:    SYMBOL_X,
+			expected: true,
+		},
+		{
+			name:     "far too many X",
+			board:    "XXX------",
+			first:    SYMBOL_X,
+			expected: false,
+		},
+		{
+			name:     "X won",
+			board:    "XXXOO----",
+			first:    SYMBOL_X,
+			expected: true,
+		},
+		{
+			name:     "O played after X won",
+			board:    "XXXOOO---",
+			first:    SYMBOL_X,
+			expected: false,
+		},
+		{
+			name:     "X played after O won",
+			board:    "XXOXO-OX-",
+			first:    SYMBOL_X,
+			expected: false,
+		},
+		{
+			name:     "X won two lines with the last move",
+			board:    "XXXOXOXOO",
+			first:    SYMBOL_X,
+			expected: true,
+		},
+		{
+			name:     "both won",
+			board:    "XXXOOOXO-",
+			first:    SYMBOL_X,
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, legalPosition(tt.board, tt.first, classicRules))
+		})
+	}
+}
+
+func TestGame_analyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		first    byte
+		expected Analysis
+	}{
+		{
+			name:  "empty board",
+			board: "---------",
+			first: SYMBOL_X,
+			expected: Analysis{
+				Board:  "---------",
+				Legal:  true,
+				Status: STATUS_RUNNING,
+				Turn:   "X",
+				Value:  STATUS_DRAW,
+			},
+		},
+		{
+			name:  "O to move",
+			board: "X---O---X",
+			first: SYMBOL_X,
+			expected: Analysis{
+				Board:  "X---O---X",
+				Legal:  true,
+				Status: STATUS_RUNNING,
+				Turn:   "O",
+				Value:  STATUS_DRAW,
+			},
+		},
+		{
+			name:  "O moved first and forces a win",
+			board: "OX--O-X--",
+			first: SYMBOL_O,
+			expected: Analysis{
+				Board:  "OX--O-X--",
+				Legal:  true,
+				Status: STATUS_RUNNING,
+				Turn:   "O",
+				Value:  STATUS_O_WON,
+			},
+		},
+		{
+			name:  "illegal",
+			board: "XXX------",
+			first: SYMBOL_X,
+			expected: Analysis{
+				Board:       "XXX------",
+				Legal:       false,
+				Status:      STATUS_X_WON,
+				WinningLine: []int{0, 1, 2},
+			},
+		},
+		{
+			name:  "fork",
+			board: "X---O-XO-",
+			first: SYMBOL_X,
+			expected: Analysis{
+				Board:  "X---O-XO-",
+				Legal:  true,
+				Status: STATUS_RUNNING,
+				Turn:   "X",
+				Value:  STATUS_X_WON,
+			},
+		},
+		{
+			name:  "won",
+			board: "XO-XO-X--",
+			first: SYMBOL_X,
+			expected: Analysis{
+				Board:       "XO-XO-X--",
+				Legal:       true,
+				Status:      STATUS_X_WON,
+				Value:       STATUS_X_WON,
+				WinningLine: []int{0, 3, 6},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, analyze(tt.board, tt.first, classicRules))
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ENV_PREFIX starts the name of the environment variable of every setting:
+// -base-url is also read from TICTACTOE_BASE_URL.
+const ENV_PREFIX = "TICTACTOE_"
+
+// Config holds the settings of a server. They are read from an optional JSON
+// file, then environment variables, then command-line flags, each overriding
+// the one before.
+type Config struct {
+	// Addr is the address the server listens on.
+	Addr string `json:"addr"`
+	// BaseURL is the public URL of the API, used in Location headers. If it
+	// is empty, URLs are built from the requests' Host and X-Forwarded-*
+	// headers.
+	BaseURL string `json:"base_url"`
+	// Storage and DataDir choose the repository, see OpenRepository.
+	Storage string `json:"storage"`
+	DataDir string `json:"data_dir"`
+	// Strategy is played in games that do not name one.
+	Strategy string `json:"strategy"`
+	// Seed seeds the server's random moves; 0 seeds them from the clock.
+	Seed int64 `json:"seed"`
+	// GinMode is debug, release or test.
+	GinMode string `json:"gin_mode"`
+	// MaxSize is the largest board size games may be started with.
+	MaxSize int `json:"max_size"`
+	// MaxGames is how many games may be stored at once; 0 means no limit.
+	MaxGames int `json:"max_games"`
+	// MaxBodyBytes is the largest request body accepted; 0 means no limit.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// ShutdownTimeout is how long open requests and streams are given to
+	// finish when the server is stopped.
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	// Snapshot is a file all games are saved to at shutdown and loaded from
+	// at startup, if set.
+	Snapshot string `json:"snapshot"`
+	// AdminToken must be sent as a bearer token to the admin endpoints, which
+	// are disabled while it is empty.
+	AdminToken string `json:"admin_token"`
+	// IdleTTL is how long a running game may go without a change, and
+	// FinishedTTL how long a finished game is kept, before they expire; 0
+	// keeps them forever. Expired games are deleted by a janitor that runs
+	// every JanitorInterval and answer 410 Gone for GoneTTL afterwards.
+	IdleTTL         Duration `json:"idle_ttl"`
+	FinishedTTL     Duration `json:"finished_ttl"`
+	GoneTTL         Duration `json:"gone_ttl"`
+	JanitorInterval Duration `json:"janitor_interval"`
+
+	// Args are the arguments left after the flags: a command and its
+	// operands.
+	Args []string `json:"-"`
+}
+
+// Duration is a time.Duration written as text, such as "10s", in flags,
+// environment variables and the config file.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// DefaultConfig returns the settings of a server that is given none.
+func DefaultConfig() Config {
+	return Config{
+		Addr:            "0.0.0.0:8080",
+		Storage:         STORAGE_MEMORY,
+		DataDir:         "data",
+		Strategy:        DEFAULT_STRATEGY,
+		GinMode:         gin.DebugMode,
+		MaxSize:         MAX_SIZE,
+		MaxBodyBytes:    1 << 20,
+		ShutdownTimeout: Duration(5 * time.Second),
+		GoneTTL:         Duration(time.Hour),
+		JanitorInterval: Duration(time.Minute),
+	}
+}
+
+// newFlagSet returns the flags of every setting, writing to c.
+func newFlagSet(c *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("tictactoe", flag.ContinueOnError)
+	fs.String("config", "", "JSON file to read settings from")
+	fs.StringVar(&c.Addr, "addr", c.Addr, "address to listen on")
+	fs.StringVar(&c.BaseURL, "base-url", c.BaseURL, "public URL of the API, taken from the requests if empty")
+	fs.StringVar(&c.Storage, "storage", c.Storage, "where games are kept: memory or file")
+	fs.StringVar(&c.DataDir, "data", c.DataDir, "directory of the file storage backend")
+	fs.StringVar(&c.Strategy, "strategy", c.Strategy, "strategy of games that do not choose one")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "seed of the server's random moves, 0 for the clock")
+	fs.StringVar(&c.GinMode, "gin-mode", c.GinMode, "gin mode: debug, release or test")
+	fs.IntVar(&c.MaxSize, "max-size", c.MaxSize, "largest board size")
+	fs.IntVar(&c.MaxGames, "max-games", c.MaxGames, "most games stored at once, 0 for no limit")
+	fs.Int64Var(&c.MaxBodyBytes, "max-body-bytes", c.MaxBodyBytes, "largest request body, 0 for no limit")
+	fs.TextVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "time open requests get to finish at shutdown")
+	fs.StringVar(&c.Snapshot, "snapshot", c.Snapshot, "file games are saved to at shutdown and loaded from at startup")
+	fs.StringVar(&c.AdminToken, "admin-token", c.AdminToken, "bearer token of the admin endpoints, which are disabled without one")
+	fs.TextVar(&c.IdleTTL, "idle-ttl", c.IdleTTL, "time after its last change a running game expires, 0 for never")
+	fs.TextVar(&c.FinishedTTL, "finished-ttl", c.FinishedTTL, "time after its last change a finished game expires, 0 for never")
+	fs.TextVar(&c.GoneTTL, "gone-ttl", c.GoneTTL, "time an expired game answers 410 Gone")
+	fs.TextVar(&c.JanitorInterval, "janitor-interval", c.JanitorInterval, "time between searches for expired games")
+	return fs
+}
+
+// envName returns the environment variable of the named flag.
+func envName(flagName string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// LoadConfig reads the configuration from the config file, the environment
+// as seen through getenv and the command-line arguments args.
+func LoadConfig(args []string, getenv func(string) string) (Config, error) {
+	parsed := DefaultConfig()
+	flags := newFlagSet(&parsed)
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	c := DefaultConfig()
+	path := getenv(envName("config"))
+	if f := flags.Lookup("config"); f.Value.String() != "" {
+		path = f.Value.String()
+	}
+	if path != "" {
+		if err := c.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	settings := newFlagSet(&c)
+	var err error
+	settings.VisitAll(func(f *flag.Flag) {
+		if value := getenv(envName(f.Name)); value != "" && f.Name != "config" && err == nil {
+			if setErr := settings.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+			}
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		_ = settings.Set(f.Name, f.Value.String())
+	})
+	if args := flags.Args(); len(args) > 0 {
+		c.Args = args
+	}
+
+	return c, c.validate()
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.GinMode != gin.DebugMode && c.GinMode != gin.ReleaseMode && c.GinMode != gin.TestMode:
+		return fmt.Errorf("unknown gin mode %q", c.GinMode)
+	case !knownStrategy(c.Strategy):
+		return fmt.Errorf("unknown strategy %q", c.Strategy)
+	case c.MaxSize < MIN_SIZE || c.MaxSize > MAX_SIZE:
+		return fmt.Errorf("max size must be between %d and %d", MIN_SIZE, MAX_SIZE)
+	case c.MaxGames < 0 || c.MaxBodyBytes < 0 || c.ShutdownTimeout < 0 ||
+		c.IdleTTL < 0 || c.FinishedTTL < 0 || c.GoneTTL < 0:
+		return fmt.Errorf("limits and timeouts must not be negative")
+	case c.JanitorInterval <= 0 && (c.IdleTTL > 0 || c.FinishedTTL > 0):
+		return fmt.Errorf("janitor interval must be positive when games expire")
+	}
+	return nil
+}
+
+func knownStrategy(name string) bool {
+	for _, known := range Strategies() {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
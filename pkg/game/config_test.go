@@ -0,0 +1,132 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_LoadConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"addr":":9000","base_url":"https://file.example","max_games":10,"shutdown_timeout":"1m"}`), 0o644))
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    func(c *Config)
+		wantErr bool
+	}{
+		{name: "defaults", want: func(c *Config) {}},
+		{
+			name: "flags",
+			args: []string{"-addr", ":9090", "-storage", STORAGE_FILE, "-seed", "42", "-max-games", "5"},
+			want: func(c *Config) {
+				c.Addr = ":9090"
+				c.Storage = STORAGE_FILE
+				c.Seed = 42
+				c.MaxGames = 5
+			},
+		},
+		{
+			name: "env",
+			env:  map[string]string{"TICTACTOE_BASE_URL": "https://example.com", "TICTACTOE_GIN_MODE": "release"},
+			want: func(c *Config) {
+				c.BaseURL = "https://example.com"
+				c.GinMode = "release"
+			},
+		},
+		{
+			name: "flags override env",
+			args: []string{"-max-size", "5"},
+			env:  map[string]string{"TICTACTOE_MAX_SIZE": "7", "TICTACTOE_STRATEGY": STRATEGY_RANDOM},
+			want: func(c *Config) {
+				c.MaxSize = 5
+				c.Strategy = STRATEGY_RANDOM
+			},
+		},
+		{
+			name: "file",
+			args: []string{"-config", file, "-max-games", "3"},
+			env:  map[string]string{"TICTACTOE_ADDR": ":9001"},
+			want: func(c *Config) {
+				c.Addr = ":9001"
+				c.BaseURL = "https://file.example"
+				c.MaxGames = 3
+				c.ShutdownTimeout = Duration(time.Minute)
+			},
+		},
+		{
+			name: "file from env",
+			env:  map[string]string{"TICTACTOE_CONFIG": file},
+			want: func(c *Config) {
+				c.Addr = ":9000"
+				c.BaseURL = "https://file.example"
+				c.MaxGames = 10
+				c.ShutdownTimeout = Duration(time.Minute)
+			},
+		},
+		{
+			name: "shutdown",
+			args: []string{"-snapshot", "games.json"},
+			env:  map[string]string{"TICTACTOE_SHUTDOWN_TIMEOUT": "30s"},
+			want: func(c *Config) {
+				c.Snapshot = "games.json"
+				c.ShutdownTimeout = Duration(30 * time.Second)
+			},
+		},
+		{
+			name: "command",
+			args: []string{"-admin-token", "secret", "export", "games.json"},
+			want: func(c *Config) {
+				c.AdminToken = "secret"
+				c.Args = []string{"export", "games.json"}
+			},
+		},
+		{
+			name: "expiry",
+			args: []string{"-idle-ttl", "2h", "-finished-ttl", "10m"},
+			env:  map[string]string{"TICTACTOE_GONE_TTL": "24h"},
+			want: func(c *Config) {
+				c.IdleTTL = Duration(2 * time.Hour)
+				c.FinishedTTL = Duration(10 * time.Minute)
+				c.GoneTTL = Duration(24 * time.Hour)
+			},
+		},
+		{
+			name: "no expiry without janitor",
+			args: []string{"-janitor-interval", "0"},
+			want: func(c *Config) {
+				c.JanitorInterval = 0
+			},
+		},
+		{name: "no janitor", args: []string{"-idle-ttl", "1h", "-janitor-interval", "0"}, wantErr: true},
+		{name: "negative ttl", args: []string{"-idle-ttl", "-1h"}, wantErr: true},
+		{name: "invalid duration", args: []string{"-shutdown-timeout", "soon"}, wantErr: true},
+		{name: "unknown flag", args: []string{"-port", "80"}, wantErr: true},
+		{name: "invalid env", env: map[string]string{"TICTACTOE_SEED": "abc"}, wantErr: true},
+		{name: "unknown strategy", args: []string{"-strategy", "cheat"}, wantErr: true},
+		{name: "unknown gin mode", args: []string{"-gin-mode", "fast"}, wantErr: true},
+		{name: "too large size", args: []string{"-max-size", "20"}, wantErr: true},
+		{name: "missing file", args: []string{"-config", filepath.Join(t.TempDir(), "missing.json")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(name string) string { return tt.env[name] }
+			config, err := LoadConfig(tt.args, getenv)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			want := DefaultConfig()
+			tt.want(&want)
+			assert.NoError(t, err)
+			assert.Equal(t, want, config)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package game
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EVENT_GAME    = "game"
+	EVENT_DELETED = "deleted"
+
+	// EVENT_BUFFER is how many events a subscriber may fall behind before
+	// older ones are dropped in favour of newer ones.
+	EVENT_BUFFER = 16
+)
+
+// Event reports a change to a game: its new state, or that it was deleted.
+type Event struct {
+	Type string
+	Game *Game
+}
+
+// broker fans out the events of each game to the subscribers of that game.
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan Event]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{
+		subscribers: make(map[uuid.UUID]map[chan Event]struct{}),
+	}
+}
+
+// subscribe returns the events of the game with the given ID and the function
+// that ends the subscription.
+func (b *broker) subscribe(id uuid.UUID) (<-chan Event, func()) {
+	events := make(chan Event, EVENT_BUFFER)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[id] == nil {
+		b.subscribers[id] = make(map[chan Event]struct{})
+	}
+	b.subscribers[id][events] = struct{}{}
+
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[id], events)
+		if len(b.subscribers[id]) == 0 {
+			delete(b.subscribers, id)
+		}
+	}
+}
+
+// publish sends event to every subscriber of the game without waiting for
+// them. A subscriber that is too far behind loses its oldest event, since
+// every event carries the whole game and the newest one matters most.
+func (b *broker) publish(id uuid.UUID, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers[id] {
+		for sent := false; !sent; {
+			select {
+			case events <- event:
+				sent = true
+			default:
+				select {
+				case <-events:
+				default:
+				}
+			}
+		}
+	}
+}
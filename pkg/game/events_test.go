@@ -0,0 +1,112 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// readEvent reads the next Server-Sent Event from r and returns its name and
+// the game it carries.
+func readEvent(t *testing.T, r *bufio.Reader) (string, *Game) {
+	name, game := "", &Game{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, game
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), game); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestBroker_publish(t *testing.T) {
+	b := newBroker()
+	id := uuid.New()
+	events, cancel := b.subscribe(id)
+
+	b.publish(uuid.New(), Event{Type: EVENT_GAME, Game: &Game{Version: 1}})
+	for version := 1; version <= EVENT_BUFFER+2; version++ {
+		b.publish(id, Event{Type: EVENT_GAME, Game: &Game{Version: version}})
+	}
+
+	assert.Len(t, events, EVENT_BUFFER)
+	assert.Equal(t, 3, (<-events).Game.Version, "the oldest events are dropped")
+
+	cancel()
+	assert.Empty(t, b.subscribers)
+	b.publish(id, Event{Type: EVENT_GAME, Game: &Game{}})
+}
+
+func TestStore_StreamEvents(t *testing.T) {
+	store := NewStore()
+	server := httptest.NewServer(store.Router)
+	defer server.Close()
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	url := fmt.Sprintf("%s/api/v1/games/%s", server.URL, game.ID.String())
+
+	resp, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := bufio.NewReader(resp.Body)
+
+	name, streamed := readEvent(t, events)
+	assert.Equal(t, EVENT_GAME, name)
+	assert.Equal(t, game.Board, streamed.Board)
+
+	move := []byte(game.Board)
+	move[strings.IndexByte(game.Board, EMPTY)] = SYMBOL_X
+	req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(fmt.Sprintf(`{"board":"%s"}`, move)))
+	moved, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved.Body.Close()
+
+	name, streamed = readEvent(t, events)
+	assert.Equal(t, EVENT_GAME, name)
+	assert.Equal(t, 2, streamed.Version)
+	assert.Len(t, streamed.Moves, 4)
+
+	req, _ = http.NewRequest("DELETE", url, nil)
+	deleted, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted.Body.Close()
+
+	name, streamed = readEvent(t, events)
+	assert.Equal(t, EVENT_DELETED, name)
+	assert.Equal(t, game.ID, streamed.ID)
+}
+
+func TestStore_StreamEventsNotFound(t *testing.T) {
+	store := NewStore()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/games/%s/events", uuid.New()), nil)
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+	assert.Empty(t, store.events.subscribers)
+}
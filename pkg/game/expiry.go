@@ -0,0 +1,117 @@
+package game
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// runJanitor expires games every janitor interval until the store shuts down.
+func (s *Store) runJanitor() {
+	ticker := time.NewTicker(s.janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.expireGames(s.now()); err != nil {
+				log.Println("expiring games:", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// expired reports whether game has outlived its TTL at now: the idle TTL
+// while it is running, the finished TTL once it is over.
+func (s *Store) expired(game *Game, now time.Time) bool {
+	ttl := s.finishedTTL
+	if game.Status == STATUS_RUNNING {
+		ttl = s.idleTTL
+	}
+	return ttl > 0 && now.Sub(game.UpdatedAt) >= ttl
+}
+
+// expireGames deletes the games that have expired at now and remembers them
+// as gone for the gone TTL. Their subscribers are told that they were
+// deleted. It returns how many games expired.
+func (s *Store) expireGames(now time.Time) (int, error) {
+	s.forgetGone(now)
+
+	games, err := s.Games.List()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, game := range games {
+		if !s.expired(game, now) {
+			continue
+		}
+		ok, err := s.expireGame(game.ID, now)
+		if err != nil {
+			return count, err
+		}
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// expireGame deletes the game with the given ID if it is still expired once
+// it is locked, since a move may have come in after the games were listed.
+func (s *Store) expireGame(id uuid.UUID, now time.Time) (bool, error) {
+	unlock := s.lockGame(id)
+	defer unlock()
+
+	game, err := s.Games.Get(id)
+	if errors.Is(err, ErrGameNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !s.expired(game, now) {
+		return false, nil
+	}
+
+	if err := s.Games.Delete(id); err != nil {
+		return false, err
+	}
+
+	if s.goneTTL > 0 {
+		s.goneMu.Lock()
+		s.gone[id] = now
+		s.goneMu.Unlock()
+	}
+	s.events.publish(id, Event{Type: EVENT_DELETED, Game: game})
+	return true, nil
+}
+
+// forgetGone drops the expired games whose gone TTL is over at now.
+func (s *Store) forgetGone(now time.Time) {
+	s.goneMu.Lock()
+	defer s.goneMu.Unlock()
+	for id, expired := range s.gone {
+		if now.Sub(expired) >= s.goneTTL {
+			delete(s.gone, id)
+		}
+	}
+}
+
+// gameError is storageError for a failure to get the game with the given ID:
+// games that expired recently are 410 Gone rather than 404 Not Found.
+func (s *Store) gameError(id uuid.UUID, err error) *apiError {
+	if errors.Is(err, ErrGameNotFound) {
+		s.goneMu.Lock()
+		expired, ok := s.gone[id]
+		s.goneMu.Unlock()
+		if ok && s.now().Sub(expired) < s.goneTTL {
+			return newAPIError(410, CODE_GAME_EXPIRED, "Game has expired")
+		}
+	}
+	return storageError(err)
+}
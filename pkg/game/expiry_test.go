@@ -0,0 +1,130 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func expiryStore(now *time.Time) *Store {
+	config := DefaultConfig()
+	config.IdleTTL = Duration(time.Hour)
+	config.FinishedTTL = Duration(time.Minute)
+	config.GoneTTL = Duration(10 * time.Minute)
+	config.JanitorInterval = 0
+	store := NewStore(WithConfig(config))
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestStore_Timestamps(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := expiryStore(&now)
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	assert.Equal(t, now, game.CreatedAt)
+	assert.Equal(t, now, game.UpdatedAt)
+
+	now = now.Add(time.Minute)
+	w := httptest.NewRecorder()
+	url := fmt.Sprintf("/api/v1/games/%s/moves", game.ID.String())
+	cell := 8
+	if game.Board[cell] != EMPTY {
+		cell = 7
+	}
+	req, _ := http.NewRequest("POST", url, bytes.NewBufferString(fmt.Sprintf(`{"cell":%d}`, cell)))
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	moved := &Game{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), moved))
+	assert.Equal(t, game.CreatedAt, moved.CreatedAt)
+	assert.Equal(t, now, moved.UpdatedAt)
+}
+
+func TestStore_ExpireGames(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		finished    bool
+		idle        time.Duration
+		wantExpired bool
+	}{
+		{name: "running", idle: 59 * time.Minute},
+		{name: "idle running", idle: time.Hour, wantExpired: true},
+		{name: "finished", finished: true, idle: 59 * time.Second},
+		{name: "old finished", finished: true, idle: time.Minute, wantExpired: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start
+			store := expiryStore(&now)
+			game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+			if tt.finished {
+				setBoard(t, store, game.ID, "XXXOO----", STATUS_X_WON)
+			}
+
+			now = now.Add(tt.idle)
+			count, err := store.expireGames(now)
+			assert.NoError(t, err)
+
+			_, w, _ := callGetSingleGame(store.Router, game.ID.String())
+			if !tt.wantExpired {
+				assert.Equal(t, 0, count)
+				assert.Equal(t, 200, w.Code)
+				return
+			}
+			assert.Equal(t, 1, count)
+			assert.Equal(t, 410, w.Code)
+			assert.Contains(t, w.Body.String(), CODE_GAME_EXPIRED)
+
+			now = now.Add(10 * time.Minute)
+			_, err = store.expireGames(now)
+			assert.NoError(t, err)
+			_, w, _ = callGetSingleGame(store.Router, game.ID.String())
+			assert.Equal(t, 404, w.Code, "expired games are forgotten after the gone TTL")
+			assert.Empty(t, store.gone)
+		})
+	}
+}
+
+func TestStore_ExpireGamesEvents(t *testing.T) {
+	now := time.Now()
+	store := expiryStore(&now)
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	events, cancel := store.events.subscribe(game.ID)
+	defer cancel()
+
+	now = now.Add(2 * time.Hour)
+	_, err := store.expireGames(now)
+	assert.NoError(t, err)
+
+	event := <-events
+	assert.Equal(t, EVENT_DELETED, event.Type)
+	assert.Equal(t, game.ID, event.Game.ID)
+}
+
+func TestGameRecord_timestamps(t *testing.T) {
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	last := first.Add(time.Minute)
+	record := gameRecord{Game: &Game{Moves: []Move{
+		{Cell: 0, Symbol: SYMBOL_X, Time: first},
+		{Cell: 4, Symbol: SYMBOL_O, Time: last},
+	}}}
+
+	game := record.game()
+	assert.Equal(t, first, game.CreatedAt)
+	assert.Equal(t, last, game.UpdatedAt)
+
+	game = gameRecord{Game: &Game{}}.game()
+	assert.False(t, game.UpdatedAt.IsZero())
+	assert.Equal(t, game.UpdatedAt, game.CreatedAt)
+}
@@ -1,40 +1,297 @@
 package game
 
 import (
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const (
-	STATUS_RUNNING = "RUNNING"
-	STATUS_X_WON   = "X_WON"
-	STATUS_O_WON   = "O_WON"
-	STATUS_DRAW    = "DRAW"
-	BOARD_LEN      = 9
-	SYMBOL_X       = 'X'
-	SYMBOL_O       = 'O'
-	EMPTY          = '-'
+	STATUS_RUNNING         = "RUNNING"
+	STATUS_X_WON           = "X_WON"
+	STATUS_O_WON           = "O_WON"
+	STATUS_DRAW            = "DRAW"
+	DEFAULT_SIZE           = 3
+	MIN_SIZE               = 3
+	MAX_SIZE               = 19
+	MIN_WIN_LENGTH         = 3
+	DEFAULT_MAX_WIN_LENGTH = 5
+	SYMBOL_X               = 'X'
+	SYMBOL_O               = 'O'
+	EMPTY                  = '-'
+	ACTOR_CLIENT           = "client"
+	ACTOR_SERVER           = "server"
+	MODE_PVC               = "pvc"
+	MODE_PVP               = "pvp"
 )
 
+// ErrInvalidSymbol is returned for a symbol that is neither X nor O.
+var ErrInvalidSymbol = errors.New("invalid symbol")
+
+// Symbol is the mark of a player, written as "X" or "O" in JSON. The zero
+// Symbol stands for no player.
+type Symbol byte
+
+func (s Symbol) MarshalText() ([]byte, error) {
+	if s == 0 {
+		return []byte{}, nil
+	}
+	return []byte{byte(s)}, nil
+}
+
+func (s *Symbol) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = 0
+		return nil
+	}
+	symbol, ok := parseSymbol(strings.ToUpper(string(text)))
+	if !ok {
+		return ErrInvalidSymbol
+	}
+	*s = Symbol(symbol)
+	return nil
+}
+
+// Move is a single mark placed on the board, by the client or the server.
+type Move struct {
+	Cell   int       `json:"cell"`
+	Symbol Symbol    `json:"symbol"`
+	Actor  string    `json:"actor"`
+	Time   time.Time `json:"time"`
+}
+
+// MoveRequest names the cell of a move, either by its index or by its row and
+// column, all counting from 0.
+type MoveRequest struct {
+	Cell *int `json:"cell"`
+	Row  *int `json:"row"`
+	Col  *int `json:"col"`
+}
+
+// cell returns the index of the requested cell on a board of the given rules,
+// or -1 if the request names no cell or one that is off the board.
+func (r MoveRequest) cell(rules Rules) int {
+	switch {
+	case r.Cell != nil && r.Row == nil && r.Col == nil:
+		if *r.Cell >= 0 && *r.Cell < rules.cells() {
+			return *r.Cell
+		}
+	case r.Cell == nil && r.Row != nil && r.Col != nil:
+		if *r.Row >= 0 && *r.Row < rules.Size && *r.Col >= 0 && *r.Col < rules.Size {
+			return *r.Row*rules.Size + *r.Col
+		}
+	}
+	return -1
+}
+
+// Rules describe the board a game is played on: a Size×Size grid where
+// WinLength marks in a row, column or diagonal win.
+type Rules struct {
+	Size      int
+	WinLength int
+}
+
+// defaultWinLength is the win length used when a game only sets its size:
+// the whole row up to 5×5, five in a row on larger boards.
+func defaultWinLength(size int) int {
+	if size < DEFAULT_MAX_WIN_LENGTH {
+		return size
+	}
+	return DEFAULT_MAX_WIN_LENGTH
+}
+
+func (r Rules) cells() int {
+	return r.Size * r.Size
+}
+
 type Game struct {
-	ID              uuid.UUID `json:"id"`
-	Board           string    `json:"board" binding:"required,len=9"`
-	Status          string    `json:"status"`
-	serverSymbol    byte
-	clientSymbol    byte
-	randomGenerator *rand.Rand
-}
-
-func (g *Game) setServerSymbol() {
-	if strings.Count(g.Board, "O") == 1 {
-		g.clientSymbol = SYMBOL_O
-		g.serverSymbol = SYMBOL_X
-	} else {
-		g.clientSymbol = SYMBOL_X
-		g.serverSymbol = SYMBOL_O
+	ID               uuid.UUID `json:"id"`
+	Board            string    `json:"board" binding:"required"`
+	Status           string    `json:"status"`
+	Mode             string    `json:"mode"`
+	Size             int       `json:"size"`
+	WinLength        int       `json:"win_length"`
+	Strategy         string    `json:"strategy"`
+	Difficulty       string    `json:"difficulty"`
+	Version          int       `json:"version"`
+	Moves            []Move    `json:"moves"`
+	UndoDisabled     bool      `json:"undo_disabled"`
+	ClientSymbol     Symbol    `json:"client_symbol,omitempty"`
+	ServerSymbol     Symbol    `json:"server_symbol,omitempty"`
+	FirstPlayer      Symbol    `json:"first_player,omitempty"`
+	WinningLine      []int     `json:"winning_line,omitempty"`
+	LastMove         *Move     `json:"last_move,omitempty"`
+	EarlyDraw        bool      `json:"early_draw"`
+	Predict          bool      `json:"predict"`
+	PredictedOutcome string    `json:"predicted_outcome,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	seats            map[string]string
+	randomGenerator  *rand.Rand
+	engine           Strategy
+}
+
+// rules returns the game's rules. Games without an explicit size take it
+// from the length of their board.
+func (g *Game) rules() Rules {
+	size := g.Size
+	if size == 0 {
+		for size*size < len(g.Board) {
+			size++
+		}
+	}
+	winLength := g.WinLength
+	if winLength == 0 {
+		winLength = defaultWinLength(size)
+	}
+	return Rules{Size: size, WinLength: winLength}
+}
+
+// validateRules falls back to a classic 3×3 board when no size was given and
+// reports false if the size or win length is out of range.
+func (g *Game) validateRules() bool {
+	if g.Size == 0 {
+		g.Size = DEFAULT_SIZE
+	}
+	if g.WinLength == 0 {
+		g.WinLength = defaultWinLength(g.Size)
+	}
+	return g.Size >= MIN_SIZE && g.Size <= MAX_SIZE &&
+		g.WinLength >= MIN_WIN_LENGTH && g.WinLength <= g.Size
+}
+
+// validateLength reports whether the board has one cell for every square of
+// the game's grid.
+func (g *Game) validateLength() bool {
+	return len(g.Board) == g.rules().cells()
+}
+
+// validateMode falls back to playing against the server when no mode was
+// given and reports false for unknown modes.
+func (g *Game) validateMode() bool {
+	if g.Mode == "" {
+		g.Mode = MODE_PVC
+	}
+	return g.Mode == MODE_PVC || g.Mode == MODE_PVP
+}
+
+// setSeats gives each player of a human-vs-human game a secret token that
+// authorizes their moves.
+func (g *Game) setSeats() error {
+	g.seats = make(map[string]string)
+	for _, symbol := range []byte{SYMBOL_X, SYMBOL_O} {
+		token := make([]byte, 16)
+		if _, err := crand.Read(token); err != nil {
+			return err
+		}
+		g.seats[string(symbol)] = hex.EncodeToString(token)
+	}
+	return nil
+}
+
+// seat returns the symbol played by the holder of token, or 0 if token is
+// not a seat of the game.
+func (g *Game) seat(token string) byte {
+	for symbol, seatToken := range g.seats {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(seatToken)) == 1 {
+			return symbol[0]
+		}
+	}
+	return 0
+}
+
+// setFirstPlayer records who opened the game: the owner of the mark on the
+// starting board if there is one, otherwise the requested first player, or
+// by default the server, or X when two people play. It reports false if the
+// requested first player did not make the opening move on the board.
+func (g *Game) setFirstPlayer() bool {
+	if opening := g.opening(); opening != 0 {
+		if g.FirstPlayer != 0 && g.FirstPlayer != opening {
+			return false
+		}
+		g.FirstPlayer = opening
+		return true
+	}
+
+	if g.FirstPlayer == 0 {
+		if g.Mode == MODE_PVP {
+			g.FirstPlayer = SYMBOL_X
+		} else {
+			g.FirstPlayer = g.ServerSymbol
+		}
+	}
+	return true
+}
+
+// opening returns the symbol of the mark on a starting board, or 0 if the
+// board is empty.
+func (g *Game) opening() Symbol {
+	if cell := strings.IndexAny(g.Board, "XO"); cell != -1 {
+		return Symbol(g.Board[cell])
+	}
+	return 0
+}
+
+// turn returns the symbol to move next.
+func (g *Game) turn() byte {
+	if g.FirstPlayer == 0 {
+		return nextTurn(g.Board, SYMBOL_X)
+	}
+	return nextTurn(g.Board, byte(g.FirstPlayer))
+}
+
+// validateDifficulty falls back to the default difficulty when none was
+// given and reports false for unknown levels.
+func (g *Game) validateDifficulty() bool {
+	if g.Difficulty == "" {
+		g.Difficulty = DEFAULT_DIFFICULTY
+	}
+	_, ok := difficultyLevels[g.Difficulty]
+	return ok
+}
+
+// setStrategy resolves the game's named strategy, falling back to the
+// default when none was given, and blends it with random play according to
+// the game's difficulty, using the game's random generator, which the store
+// attaches. It reports false for unknown names.
+func (g *Game) setStrategy() bool {
+	if g.Strategy == "" {
+		g.Strategy = DEFAULT_STRATEGY
+	}
+	engine, ok := newStrategy(g.Strategy, g.randomGenerator)
+	if !ok {
+		return false
+	}
+	if !g.validateDifficulty() {
+		g.Difficulty = DEFAULT_DIFFICULTY
+	}
+	if rate := difficultyLevels[g.Difficulty]; rate < 100 {
+		engine = blend(engine, rate, g.randomGenerator)
+	}
+	g.engine = engine
+	return true
+}
+
+// setServerSymbol gives the server the symbol the client does not play. The
+// client plays the mark on the starting board, or the requested symbol, or X.
+// It reports false if the requested symbol is not the one on the board.
+func (g *Game) setServerSymbol() bool {
+	if opening := g.opening(); opening != 0 {
+		if g.ClientSymbol != 0 && g.ClientSymbol != opening {
+			return false
+		}
+		g.ClientSymbol = opening
+	} else if g.ClientSymbol == 0 {
+		g.ClientSymbol = SYMBOL_X
 	}
+	g.ServerSymbol = Symbol(opponent(byte(g.ClientSymbol)))
+	return true
 }
 
 func (g *Game) validateFirstInput() bool {
@@ -43,37 +300,146 @@ func (g *Game) validateFirstInput() bool {
 	return countO+countX <= 1
 }
 
-func (g *Game) validateBoard() bool {
-	for _, c := range g.Board {
-		if c != EMPTY && c != SYMBOL_O && c != SYMBOL_X {
-			return false
+// invalidCell returns the first cell of the board that holds neither a
+// symbol nor EMPTY, or -1 if there is none.
+func (g *Game) invalidCell() int {
+	for i := 0; i < len(g.Board); i++ {
+		if c := g.Board[i]; c != EMPTY && c != SYMBOL_O && c != SYMBOL_X {
+			return i
 		}
 	}
-	return true
+	return -1
 }
 
-func (g *Game) validateMove(next *Game) bool {
-	moves := 0
-	for i := 0; i < BOARD_LEN; i++ {
-		if g.Board[i] == EMPTY && g.Board[i] != next.Board[i] {
-			moves++
-			if moves > 1 {
-				return false
-			}
-			if next.Board[i] != g.clientSymbol {
-				return false
-			}
-		} else if g.Board[i] != next.Board[i] {
-			return false
+// validatePosition reports whether the game's board can be reached by
+// alternating moves, starting with the game's first player, and whether it
+// leaves anything to play for.
+func (g *Game) validatePosition() bool {
+	rules := g.rules()
+	return legalPosition(g.Board, byte(g.FirstPlayer), rules) && evaluate(g.Board, rules) == STATUS_RUNNING
+}
+
+// checkMove explains why next is not a legal move of symbol: the game must
+// still be open, it must be symbol's turn, and next must add exactly one mark
+// of symbol to the game's board and change nothing else.
+func (g *Game) checkMove(next *Game, symbol byte) *apiError {
+	if len(next.Board) != len(g.Board) {
+		return errInvalidLength
+	}
+	if evaluate(g.Board, g.rules()) != STATUS_RUNNING {
+		return errGameFinished
+	}
+	if symbol != g.turn() {
+		return errOutOfTurn
+	}
+	if cell := next.invalidCell(); cell != -1 {
+		return errInvalidCharacter.atCell(cell)
+	}
+
+	moved := false
+	for i := 0; i < len(g.Board); i++ {
+		switch {
+		case g.Board[i] == next.Board[i]:
+			continue
+		case next.Board[i] == EMPTY:
+			return errMarkRemoved.atCell(i)
+		case g.Board[i] != EMPTY:
+			return errCellOccupied.atCell(i)
+		case next.Board[i] != symbol:
+			return errWrongSymbol.atCell(i)
+		case moved:
+			return errMultipleMoves.atCell(i)
 		}
+		moved = true
+	}
+	if !moved {
+		return errNoMove
+	}
+	return nil
+}
+
+// changedCell returns the first cell that differs between the game's board
+// and next, or -1 if they are the same.
+func (g *Game) changedCell(next *Game) int {
+	for i := 0; i < len(g.Board) && i < len(next.Board); i++ {
+		if g.Board[i] != next.Board[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// play marks cell with symbol and records the move.
+func (g *Game) play(cell int, symbol byte, actor string) {
+	g.Board = replaceAtIndex(g.Board, symbol, cell)
+	g.Moves = append(g.Moves, Move{
+		Cell:   cell,
+		Symbol: Symbol(symbol),
+		Actor:  actor,
+		Time:   time.Now().UTC(),
+	})
+	g.setLastMove()
+}
+
+// setLastMove points LastMove at a copy of the last recorded move.
+func (g *Game) setLastMove() {
+	g.LastMove = nil
+	if len(g.Moves) > 0 {
+		last := g.Moves[len(g.Moves)-1]
+		g.LastMove = &last
+	}
+}
+
+// move plays cell for symbol and, in games against the server, the server's
+// answer, and updates the status after each of them.
+func (g *Game) move(cell int, symbol byte) {
+	g.play(cell, symbol, ACTOR_CLIENT)
+	g.updateStatus()
+	if g.Mode == MODE_PVP || g.Status != STATUS_RUNNING {
+		return
+	}
+
+	g.makeCounterMove()
+	g.updateStatus()
+}
+
+// undo takes back the client's last move together with the server's reply,
+// and reopens the game if either of them ended it. It reports false if the
+// client has no move to take back.
+func (g *Game) undo() bool {
+	last := -1
+	for i := len(g.Moves) - 1; i >= 0; i-- {
+		if g.Moves[i].Actor == ACTOR_CLIENT {
+			last = i
+			break
+		}
+	}
+	if last == -1 {
+		return false
 	}
+
+	for _, move := range g.Moves[last:] {
+		g.Board = replaceAtIndex(g.Board, EMPTY, move.Cell)
+	}
+	g.Moves = g.Moves[:last]
+	g.setLastMove()
+	g.Status = STATUS_RUNNING
+	g.WinningLine = nil
 	return true
 }
 
 func (g *Game) makeCounterMove() {
-	emptyCells := g.findEmptyCells()
-	randomIndex := emptyCells[g.randomGenerator.Intn(len(emptyCells))]
-	g.Board = replaceAtIndex(g.Board, g.serverSymbol, randomIndex)
+	if g.engine == nil && !g.setStrategy() {
+		g.engine = StrategyFunc(bestMove)
+	}
+	symbol := byte(g.ServerSymbol)
+	cell := g.engine.NextMove(g.Board, symbol, g.rules())
+	// Registered strategies are not trusted: a move off the board or onto a
+	// taken cell is replaced by the best one.
+	if cell < 0 || cell >= len(g.Board) || g.Board[cell] != EMPTY {
+		cell = bestMove(g.Board, symbol, g.rules())
+	}
+	g.play(cell, symbol, ACTOR_SERVER)
 }
 
 func (g *Game) findEmptyCells() []int {
@@ -92,45 +458,62 @@ func replaceAtIndex(board string, symbol byte, i int) string {
 	return string(out)
 }
 
-func (g *Game) setStatus(idx int) {
-	if g.Board[idx] == SYMBOL_O {
+func (g *Game) setStatus(line ...int) {
+	if g.Board[line[0]] == SYMBOL_O {
 		g.Status = STATUS_O_WON
 	} else {
 		g.Status = STATUS_X_WON
 	}
+	g.WinningLine = line
 }
 
-func (g *Game) checkRows() bool {
-	for i := 0; i < 9; i += 3 {
-		if g.Board[i] != EMPTY && g.Board[i] == g.Board[i+1] && g.Board[i+1] == g.Board[i+2] {
-			g.setStatus(i)
-			return true
+// lines calls check with every run of WinLength cells that goes in direction
+// (dRow, dCol) and stops as soon as check returns true.
+func (g *Game) lines(dRow, dCol int, check func(line []int) bool) bool {
+	rules := g.rules()
+	line := make([]int, rules.WinLength)
+	for row := 0; row < rules.Size; row++ {
+		for col := 0; col < rules.Size; col++ {
+			endRow := row + dRow*(rules.WinLength-1)
+			endCol := col + dCol*(rules.WinLength-1)
+			if endRow < 0 || endRow >= rules.Size || endCol < 0 || endCol >= rules.Size {
+				continue
+			}
+			for i := range line {
+				line[i] = (row+dRow*i)*rules.Size + col + dCol*i
+			}
+			if check(line) {
+				return true
+			}
 		}
 	}
 	return false
 }
 
-func (g *Game) checkCols() bool {
-	for i := 0; i < 3; i++ {
-		if g.Board[i] != EMPTY && g.Board[i] == g.Board[i+3] && g.Board[i+3] == g.Board[i+6] {
-			g.setStatus(i)
-			return true
+// checkLine sets the status if every cell of line holds the same symbol.
+func (g *Game) checkLine(line []int) bool {
+	if g.Board[line[0]] == EMPTY {
+		return false
+	}
+	for _, i := range line[1:] {
+		if g.Board[i] != g.Board[line[0]] {
+			return false
 		}
 	}
-	return false
+	g.setStatus(append([]int(nil), line...)...)
+	return true
 }
 
-func (g *Game) checkDiagonal() bool {
-	if g.Board[0] != EMPTY && g.Board[0] == g.Board[4] && g.Board[4] == g.Board[8] {
-		g.setStatus(0)
-		return true
-	}
+func (g *Game) checkRows() bool {
+	return g.lines(0, 1, g.checkLine)
+}
 
-	if g.Board[2] != EMPTY && g.Board[2] == g.Board[4] && g.Board[4] == g.Board[6] {
-		g.setStatus(2)
-		return true
-	}
-	return false
+func (g *Game) checkCols() bool {
+	return g.lines(1, 0, g.checkLine)
+}
+
+func (g *Game) checkDiagonal() bool {
+	return g.lines(1, 1, g.checkLine) || g.lines(1, -1, g.checkLine)
 }
 
 func (g *Game) checkDraw() bool {
@@ -141,9 +524,51 @@ func (g *Game) checkDraw() bool {
 	return false
 }
 
+// checkDeadDraw ends games played with EarlyDraw as a draw as soon as neither
+// player can complete a line any more: every line either holds both symbols
+// or needs more marks than its owner has moves left.
+func (g *Game) checkDeadDraw() bool {
+	if !g.EarlyDraw || g.Status != STATUS_RUNNING {
+		return false
+	}
+	empty := len(g.findEmptyCells())
+	next := g.turn()
+	left := map[byte]int{next: (empty + 1) / 2, opponent(next): empty / 2}
+	open := func(line []int) bool {
+		x, o, free := false, false, 0
+		for _, i := range line {
+			x = x || g.Board[i] == SYMBOL_X
+			o = o || g.Board[i] == SYMBOL_O
+			if g.Board[i] == EMPTY {
+				free++
+			}
+		}
+		return (!o && free <= left[SYMBOL_X]) || (!x && free <= left[SYMBOL_O])
+	}
+	if g.lines(0, 1, open) || g.lines(1, 0, open) || g.lines(1, 1, open) || g.lines(1, -1, open) {
+		return false
+	}
+	g.Status = STATUS_DRAW
+	return true
+}
+
 func (g *Game) updateStatus() {
 	_ = g.checkRows() ||
 		g.checkCols() ||
 		g.checkDiagonal() ||
-		g.checkDraw()
+		g.checkDraw() ||
+		g.checkDeadDraw()
+}
+
+// predictOutcome sets PredictedOutcome to the status the game ends with when
+// both sides play perfectly from here, for games played with Predict.
+func (g *Game) predictOutcome() {
+	switch {
+	case !g.Predict:
+		g.PredictedOutcome = ""
+	case g.Status != STATUS_RUNNING:
+		g.PredictedOutcome = g.Status
+	default:
+		g.PredictedOutcome = perfectPlay(g.Board, g.turn(), g.rules())
+	}
 }
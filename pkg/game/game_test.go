@@ -11,7 +11,7 @@ func TestGame_setServerSymbol(t *testing.T) {
 	tests := []struct {
 		name        string
 		board       string
-		expectedSymbol byte
+		expectedSymbol Symbol
 	}{
 		{
 			name:        "empty board",
@@ -41,7 +41,7 @@ func TestGame_setServerSymbol(t *testing.T) {
 			/*if g.serverSymbol != tt.expectedSym {
 				t.Errorf("Expected server symbol to be %c, but got %c", tt.expectedSym, g.serverSymbol)
 			}*/
-			assert.Equal(t, g.serverSymbol, tt.expectedSymbol)
+			assert.Equal(t, g.ServerSymbol, tt.expectedSymbol)
 		})
 	}
 }
@@ -90,9 +90,19 @@ func TestGame_makeCounterMove(t *testing.T) {
 			expectedBoard: "O--------",
 		},
 		{
-			name:          "first is occupied",
+			name:          "corner opening",
 			board:         "X--------",
-			expectedBoard: "X--O-----",
+			expectedBoard: "X---O----",
+		},
+		{
+			name:          "take the win",
+			board:         "XX-OO-X--",
+			expectedBoard: "XX-OOOX--",
+		},
+		{
+			name:          "block the win",
+			board:         "XX--O----",
+			expectedBoard: "XXO-O----",
 		},
 	}
 	for _, tt := range tests {
@@ -100,7 +110,7 @@ func TestGame_makeCounterMove(t *testing.T) {
 			g := &Game{
 				Board:           tt.board,
 				randomGenerator: rand.New(rand.NewSource(0)),
-				serverSymbol:    SYMBOL_O,
+				ServerSymbol:    SYMBOL_O,
 			}
 			g.makeCounterMove()
 			assert.Equal(t, g.Board, tt.expectedBoard)
@@ -207,7 +217,7 @@ func TestGame_validateBoard(t *testing.T) {
 			g := &Game{
 				Board: tt.board,
 			}
-			assert.Equal(t, g.validateBoard(), tt.expected)
+			assert.Equal(t, g.invalidCell() == -1, tt.expected)
 		})
 	}
 }
@@ -233,15 +243,21 @@ func TestGame_validateMove(t *testing.T) {
 		},
 		{
 			name:     "valid full",
-			board:    "OXOXOXOX-",
-			next:     "OXOXOXOXX",
+			board:    "-XOOXXXOO",
+			next:     "XXOOXXXOO",
 			expected: true,
 		},
 		{
-			name:     "invalid full",
+			name:     "invalid after a win",
 			board:    "OXOXOXOX-",
 			next:     "OXOXOXOXX",
-			expected: true,
+			expected: false,
+		},
+		{
+			name:     "invalid out of turn",
+			board:    "X-OX-----",
+			next:     "X-OXX----",
+			expected: false,
 		},
 		{
 			name:     "invalid move >1",
@@ -261,18 +277,24 @@ func TestGame_validateMove(t *testing.T) {
 			next:     "--O---a--",
 			expected: false,
 		},
+		{
+			name:     "invalid no move",
+			board:    "--O------",
+			next:     "--O------",
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := &Game{
 				Board:        tt.board,
-				clientSymbol: SYMBOL_X,
+				ClientSymbol: SYMBOL_X,
 			}
 			next := &Game{
 				Board: tt.next,
 			}
-			assert.Equal(t, g.validateMove(next), tt.expected)
+			assert.Equal(t, g.checkMove(next, SYMBOL_X) == nil, tt.expected)
 		})
 	}
 }
@@ -470,3 +492,384 @@ func TestGame_checkDraw(t *testing.T) {
 		})
 	}
 }
+
+func TestGame_validateRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		size          int
+		winLength     int
+		expected      bool
+		wantSize      int
+		wantWinLength int
+	}{
+		{
+			name:          "defaults",
+			expected:      true,
+			wantSize:      3,
+			wantWinLength: 3,
+		},
+		{
+			name:          "4x4",
+			size:          4,
+			expected:      true,
+			wantSize:      4,
+			wantWinLength: 4,
+		},
+		{
+			name:          "5x5 four in a row",
+			size:          5,
+			winLength:     4,
+			expected:      true,
+			wantSize:      5,
+			wantWinLength: 4,
+		},
+		{
+			name:          "gomoku",
+			size:          15,
+			expected:      true,
+			wantSize:      15,
+			wantWinLength: 5,
+		},
+		{
+			name:          "too small",
+			size:          2,
+			expected:      false,
+			wantSize:      2,
+			wantWinLength: 2,
+		},
+		{
+			name:          "too large",
+			size:          20,
+			expected:      false,
+			wantSize:      20,
+			wantWinLength: 5,
+		},
+		{
+			name:          "win length longer than the board",
+			size:          4,
+			winLength:     5,
+			expected:      false,
+			wantSize:      4,
+			wantWinLength: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{
+				Size:      tt.size,
+				WinLength: tt.winLength,
+			}
+			assert.Equal(t, tt.expected, g.validateRules())
+			assert.Equal(t, tt.wantSize, g.Size)
+			assert.Equal(t, tt.wantWinLength, g.WinLength)
+		})
+	}
+}
+
+func TestGame_updateStatusLargeBoards(t *testing.T) {
+	tests := []struct {
+		name      string
+		board     string
+		size      int
+		winLength int
+		expected  string
+		wantLine  []int
+	}{
+		{
+			name: "4x4 row",
+			board: "----" +
+				"XXXX" +
+				"OOO-" +
+				"----",
+			size:      4,
+			winLength: 4,
+			expected:  STATUS_X_WON,
+			wantLine:  []int{4, 5, 6, 7},
+		},
+		{
+			name: "4x4 three is not enough",
+			board: "----" +
+				"XXX-" +
+				"OO--" +
+				"----",
+			size:      4,
+			winLength: 4,
+			expected:  STATUS_RUNNING,
+		},
+		{
+			name: "5x5 column of four",
+			board: "-----" +
+				"-O---" +
+				"-O---" +
+				"-O--X" +
+				"-O-XX",
+			size:      5,
+			winLength: 4,
+			expected:  STATUS_O_WON,
+			wantLine:  []int{6, 11, 16, 21},
+		},
+		{
+			name: "5x5 diagonal of four",
+			board: "X----" +
+				"-X---" +
+				"--X-O" +
+				"---XO" +
+				"--OO-",
+			size:      5,
+			winLength: 4,
+			expected:  STATUS_X_WON,
+			wantLine:  []int{0, 6, 12, 18},
+		},
+		{
+			name: "5x5 anti-diagonal of four",
+			board: "-----" +
+				"----O" +
+				"---O-" +
+				"--O-X" +
+				"-O-XX",
+			size:      5,
+			winLength: 4,
+			expected:  STATUS_O_WON,
+			wantLine:  []int{9, 13, 17, 21},
+		},
+		{
+			name:      "4x4 draw",
+			board:     "XOXO" + "XOXO" + "OXOX" + "OXOX",
+			size:      4,
+			winLength: 4,
+			expected:  STATUS_DRAW,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{
+				Board:     tt.board,
+				Size:      tt.size,
+				WinLength: tt.winLength,
+				Status:    STATUS_RUNNING,
+			}
+			g.updateStatus()
+			assert.Equal(t, tt.expected, g.Status)
+			assert.Equal(t, tt.wantLine, g.WinningLine)
+		})
+	}
+}
+
+func TestGame_undo(t *testing.T) {
+	tests := []struct {
+		name       string
+		board      string
+		status     string
+		moves      []Move
+		expected   bool
+		wantBoard  string
+		wantMoves  int
+		wantStatus string
+	}{
+		{
+			name:   "client and server move",
+			board:  "XO--X---O",
+			status: STATUS_RUNNING,
+			moves: []Move{
+				{Cell: 0, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+				{Cell: 1, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+				{Cell: 4, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+				{Cell: 8, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+			},
+			expected:   true,
+			wantBoard:  "XO-------",
+			wantMoves:  2,
+			wantStatus: STATUS_RUNNING,
+		},
+		{
+			name:   "client won",
+			board:  "XXXOO----",
+			status: STATUS_X_WON,
+			moves: []Move{
+				{Cell: 0, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+				{Cell: 3, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+				{Cell: 1, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+				{Cell: 4, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+				{Cell: 2, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+			},
+			expected:   true,
+			wantBoard:  "XX-OO----",
+			wantMoves:  4,
+			wantStatus: STATUS_RUNNING,
+		},
+		{
+			name:   "server won",
+			board:  "OOOXX-X--",
+			status: STATUS_O_WON,
+			moves: []Move{
+				{Cell: 0, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+				{Cell: 3, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+				{Cell: 1, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+				{Cell: 4, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+				{Cell: 6, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+				{Cell: 2, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+			},
+			expected:   true,
+			wantBoard:  "OO-XX----",
+			wantMoves:  4,
+			wantStatus: STATUS_RUNNING,
+		},
+		{
+			name:   "only the server moved",
+			board:  "----O----",
+			status: STATUS_RUNNING,
+			moves: []Move{
+				{Cell: 4, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+			},
+			expected:   false,
+			wantBoard:  "----O----",
+			wantMoves:  1,
+			wantStatus: STATUS_RUNNING,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{
+				Board:  tt.board,
+				Status: tt.status,
+				Moves:  tt.moves,
+			}
+			assert.Equal(t, tt.expected, g.undo())
+			assert.Equal(t, tt.wantBoard, g.Board)
+			assert.Len(t, g.Moves, tt.wantMoves)
+			assert.Equal(t, tt.wantStatus, g.Status)
+		})
+	}
+}
+
+func TestGame_turn(t *testing.T) {
+	tests := []struct {
+		name  string
+		board string
+		first byte
+		want  byte
+	}{
+		{name: "X opens", board: "---------", first: SYMBOL_X, want: SYMBOL_X},
+		{name: "O opens", board: "---------", first: SYMBOL_O, want: SYMBOL_O},
+		{name: "O answers", board: "X--------", first: SYMBOL_X, want: SYMBOL_O},
+		{name: "X answers", board: "O--------", first: SYMBOL_O, want: SYMBOL_X},
+		{name: "unknown opener", board: "X---O----", first: 0, want: SYMBOL_X},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Board: tt.board, FirstPlayer: Symbol(tt.first)}
+			assert.Equal(t, tt.want, g.turn())
+		})
+	}
+}
+
+func TestMoveRequest_cell(t *testing.T) {
+	one, four, five := 1, 4, 5
+	minus := -1
+	tests := []struct {
+		name    string
+		request MoveRequest
+		rules   Rules
+		want    int
+	}{
+		{name: "cell", request: MoveRequest{Cell: &four}, rules: Rules{Size: 3}, want: 4},
+		{name: "row and col", request: MoveRequest{Row: &one, Col: &one}, rules: Rules{Size: 3}, want: 4},
+		{name: "row and col on 5x5", request: MoveRequest{Row: &one, Col: &four}, rules: Rules{Size: 5}, want: 9},
+		{name: "nothing", request: MoveRequest{}, rules: Rules{Size: 3}, want: -1},
+		{name: "row only", request: MoveRequest{Row: &one}, rules: Rules{Size: 3}, want: -1},
+		{name: "cell and row", request: MoveRequest{Cell: &four, Row: &one, Col: &one}, rules: Rules{Size: 3}, want: -1},
+		{name: "negative cell", request: MoveRequest{Cell: &minus}, rules: Rules{Size: 3}, want: -1},
+		{name: "col off the board", request: MoveRequest{Row: &one, Col: &five}, rules: Rules{Size: 3}, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.request.cell(tt.rules))
+		})
+	}
+}
+
+func TestGame_validatePosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		size     int
+		first    Symbol
+		expected bool
+	}{
+		{name: "empty", board: "---------", first: SYMBOL_X, expected: true},
+		{name: "opening move", board: "----O----", first: SYMBOL_O, expected: true},
+		{name: "opening move by the second player", board: "----O----", first: SYMBOL_X, expected: false},
+		{name: "two moves in a row", board: "XX--O-X--", first: SYMBOL_X, expected: false},
+		{name: "already won", board: "XXXOO----", first: SYMBOL_X, expected: false},
+		{name: "play after a win", board: "XXXOO-O--", first: SYMBOL_X, expected: false},
+		{name: "two winners", board: "XXXOOO---", first: SYMBOL_X, expected: false},
+		{name: "drawn", board: "XXOOOXXOX", first: SYMBOL_X, expected: false},
+		{name: "large board", board: "------------X---", size: 4, first: SYMBOL_X, expected: true},
+		{name: "large board out of turn", board: "------------X--X", size: 4, first: SYMBOL_X, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Board: tt.board, Size: tt.size, FirstPlayer: tt.first}
+			assert.Equal(t, tt.expected, g.validatePosition())
+		})
+	}
+}
+
+func TestGame_checkDeadDraw(t *testing.T) {
+	tests := []struct {
+		name      string
+		board     string
+		earlyDraw bool
+		expected  string
+	}{
+		{name: "dead position", board: "XXOOOXXO-", earlyDraw: true, expected: STATUS_DRAW},
+		{name: "dead position without early draws", board: "XXOOOXXO-", earlyDraw: false, expected: STATUS_RUNNING},
+		{name: "line still open", board: "XXOOO-XO-", earlyDraw: true, expected: STATUS_RUNNING},
+		{name: "empty line out of reach", board: "XOX---OXO", earlyDraw: true, expected: STATUS_DRAW},
+		{name: "line out of reach with O to move", board: "XXOOOXX--", earlyDraw: true, expected: STATUS_DRAW},
+		{name: "line within reach", board: "XOX-O-OX-", earlyDraw: true, expected: STATUS_RUNNING},
+		{name: "win beats dead position", board: "XXXOO-OO-", earlyDraw: true, expected: STATUS_X_WON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Board: tt.board, Status: STATUS_RUNNING, EarlyDraw: tt.earlyDraw}
+			g.updateStatus()
+			assert.Equal(t, tt.expected, g.Status)
+		})
+	}
+}
+
+func TestGame_checkMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		next     string
+		wantCode string
+		wantCell int
+	}{
+		{name: "valid", board: "X---O----", next: "XX--O----", wantCode: "", wantCell: -1},
+		{name: "length", board: "X---O----", next: "XX--O---", wantCode: CODE_INVALID_LENGTH, wantCell: -1},
+		{name: "character", board: "X---O----", next: "X---O--a-", wantCode: CODE_INVALID_CHARACTER, wantCell: 7},
+		{name: "occupied", board: "X---O----", next: "X---X----", wantCode: CODE_CELL_OCCUPIED, wantCell: 4},
+		{name: "removed", board: "X---O----", next: "----OX---", wantCode: CODE_MARK_REMOVED, wantCell: 0},
+		{name: "wrong symbol", board: "X---O----", next: "X---O-O--", wantCode: CODE_WRONG_SYMBOL, wantCell: 6},
+		{name: "multiple", board: "X---O----", next: "XX--OX---", wantCode: CODE_MULTIPLE_MOVES, wantCell: 5},
+		{name: "no move", board: "X---O----", next: "X---O----", wantCode: CODE_NO_MOVE, wantCell: -1},
+		{name: "finished", board: "XXXOO----", next: "XXXOOX---", wantCode: CODE_GAME_FINISHED, wantCell: -1},
+		{name: "out of turn", board: "X--------", next: "X---X----", wantCode: CODE_OUT_OF_TURN, wantCell: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{Board: tt.board}
+			err := g.checkMove(&Game{Board: tt.next}, SYMBOL_X)
+			if tt.wantCode == "" {
+				assert.Nil(t, err)
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.wantCode, err.code)
+				assert.Equal(t, tt.wantCell, err.cell)
+			}
+		})
+	}
+}
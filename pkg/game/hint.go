@@ -0,0 +1,62 @@
+package game
+
+const (
+	OUTCOME_WIN  = "WIN"
+	OUTCOME_DRAW = "DRAW"
+	OUTCOME_LOSS = "LOSS"
+)
+
+// Hint recommends the next move of a player. Outcome is the result the player
+// gets by following the recommendation while both sides play perfectly.
+// Scores covers every empty cell of boards small enough to search to the end
+// and the cells next to a mark on larger ones.
+type Hint struct {
+	Cell    int         `json:"cell"`
+	Outcome string      `json:"outcome"`
+	Scores  []CellScore `json:"scores"`
+}
+
+// CellScore is the minimax score of playing Cell. Scores close to SCORE_WIN
+// lead to a win and scores close to -SCORE_WIN to a loss. On boards too large
+// to search to the end, anything in between is an estimate of the position
+// and reported as a draw.
+type CellScore struct {
+	Cell    int    `json:"cell"`
+	Score   int    `json:"score"`
+	Outcome string `json:"outcome"`
+}
+
+func outcome(score int) string {
+	switch {
+	case score > SCORE_WIN/2:
+		return OUTCOME_WIN
+	case score < -SCORE_WIN/2:
+		return OUTCOME_LOSS
+	}
+	return OUTCOME_DRAW
+}
+
+func (g *Game) hint(symbol byte) Hint {
+	scores := scoreMoves(g.Board, symbol, g.rules())
+	hint := Hint{
+		Cell:   -1,
+		Scores: make([]CellScore, 0, len(scores)),
+	}
+	for _, i := range g.findEmptyCells() {
+		if _, ok := scores[i]; !ok {
+			continue
+		}
+		hint.Scores = append(hint.Scores, CellScore{
+			Cell:    i,
+			Score:   scores[i],
+			Outcome: outcome(scores[i]),
+		})
+		if hint.Cell == -1 || scores[i] > scores[hint.Cell] {
+			hint.Cell = i
+		}
+	}
+	if hint.Cell != -1 {
+		hint.Outcome = outcome(scores[hint.Cell])
+	}
+	return hint
+}
@@ -0,0 +1,57 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGame_hint(t *testing.T) {
+	tests := []struct {
+		name        string
+		board       string
+		wantCell    int
+		wantOutcome string
+		wantScores  int
+	}{
+		{
+			name:        "take the win",
+			board:       "XX-OO----",
+			wantCell:    2,
+			wantOutcome: OUTCOME_WIN,
+			wantScores:  5,
+		},
+		{
+			name:        "double threat",
+			board:       "O-O-X-O-X",
+			wantCell:    1,
+			wantOutcome: OUTCOME_LOSS,
+			wantScores:  4,
+		},
+		{
+			name:        "opening",
+			board:       "---------",
+			wantCell:    0,
+			wantOutcome: OUTCOME_DRAW,
+			wantScores:  9,
+		},
+		{
+			name:        "full board",
+			board:       "XOXOXOOXO",
+			wantCell:    -1,
+			wantOutcome: "",
+			wantScores:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Game{
+				Board: tt.board,
+			}
+			hint := g.hint(SYMBOL_X)
+			assert.Equal(t, tt.wantCell, hint.Cell)
+			assert.Equal(t, tt.wantOutcome, hint.Outcome)
+			assert.Len(t, hint.Scores, tt.wantScores)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package game
+
+import "math"
+
+const (
+	SCORE_WIN  = 1 << 20
+	SCORE_DRAW = 0
+
+	// EXACT_SEARCH_CELLS is the largest number of empty cells that is searched
+	// to the end of the game. Fuller boards are searched as deep as
+	// SEARCH_BUDGET allows and their leaves scored heuristically.
+	EXACT_SEARCH_CELLS = 9
+	SEARCH_BUDGET      = 20000
+	MIN_SEARCH_DEPTH   = 2
+	MIN_BRANCHING      = 8
+)
+
+func opponent(symbol byte) byte {
+	if symbol == SYMBOL_X {
+		return SYMBOL_O
+	}
+	return SYMBOL_X
+}
+
+func wonStatus(symbol byte) string {
+	if symbol == SYMBOL_X {
+		return STATUS_X_WON
+	}
+	return STATUS_O_WON
+}
+
+// evaluate runs the game's win and draw checks against board and returns the
+// resulting status.
+func evaluate(board string, rules Rules) string {
+	g := &Game{Board: board, Size: rules.Size, WinLength: rules.WinLength, Status: STATUS_RUNNING}
+	g.updateStatus()
+	return g.Status
+}
+
+// search holds the parameters of a single minimax search. A maxDepth of zero
+// searches to the end of the game.
+type search struct {
+	rules    Rules
+	maxDepth int
+}
+
+// newSearch sizes a search for board: small positions are solved exactly,
+// larger ones as deep as the branching factor allows within SEARCH_BUDGET.
+func newSearch(board string, rules Rules) *search {
+	s := &search{rules: rules}
+	empty := len((&Game{Board: board}).findEmptyCells())
+	if empty <= EXACT_SEARCH_CELLS {
+		return s
+	}
+
+	s.maxDepth = MIN_SEARCH_DEPTH
+	branching := len(s.candidates(board))
+	if branching < MIN_BRANCHING {
+		branching = MIN_BRANCHING
+	}
+	for positions := branching * branching; positions*branching <= SEARCH_BUDGET && s.maxDepth < empty; positions *= branching {
+		s.maxDepth++
+	}
+	return s
+}
+
+// candidates returns the cells worth searching on board. An exact search
+// tries every empty cell; a depth-limited one only the empty cells next to a
+// mark, or the centre of an empty board.
+func (s *search) candidates(board string) []int {
+	emptyCells := (&Game{Board: board}).findEmptyCells()
+	if s.maxDepth == 0 {
+		return emptyCells
+	}
+
+	cells := make([]int, 0)
+	for _, i := range emptyCells {
+		row, col := i/s.rules.Size, i%s.rules.Size
+	neighbours:
+		for r := row - 1; r <= row+1; r++ {
+			for c := col - 1; c <= col+1; c++ {
+				if r >= 0 && r < s.rules.Size && c >= 0 && c < s.rules.Size && board[r*s.rules.Size+c] != EMPTY {
+					cells = append(cells, i)
+					break neighbours
+				}
+			}
+		}
+	}
+	if len(cells) == 0 && len(emptyCells) > 0 {
+		return []int{s.rules.Size / 2 * (s.rules.Size + 1)}
+	}
+	return cells
+}
+
+// bestMove returns the index of the cell that gives symbol the best result
+// under perfect play from both sides, as far as the search looks ahead. Ties
+// go to the lowest index. It returns -1 if the board has no empty cells.
+func bestMove(board string, symbol byte, rules Rules) int {
+	s := newSearch(board, rules)
+	best := -1
+	alpha, beta := math.MinInt, math.MaxInt
+	for _, i := range s.candidates(board) {
+		score := s.minimax(replaceAtIndex(board, symbol, i), symbol, opponent(symbol), 1, alpha, beta)
+		if best == -1 || score > alpha {
+			best = i
+			alpha = score
+		}
+	}
+	return best
+}
+
+// scoreMoves returns the minimax score of every cell worth searching on board
+// if symbol plays it next.
+func scoreMoves(board string, symbol byte, rules Rules) map[int]int {
+	s := newSearch(board, rules)
+	scores := make(map[int]int)
+	for _, i := range s.candidates(board) {
+		scores[i] = s.minimax(replaceAtIndex(board, symbol, i), symbol, opponent(symbol), 1, math.MinInt, math.MaxInt)
+	}
+	return scores
+}
+
+// solve returns the minimax score of board for turn, the symbol to move.
+func solve(board string, turn byte, rules Rules) int {
+	return newSearch(board, rules).minimax(board, turn, turn, 0, math.MinInt, math.MaxInt)
+}
+
+// minimax scores board from the point of view of player, with turn to move
+// next. Wins are worth more the sooner they happen and losses cost less the
+// later they happen, so the engine prefers quick wins and slow defeats.
+func (s *search) minimax(board string, player, turn byte, depth, alpha, beta int) int {
+	switch evaluate(board, s.rules) {
+	case wonStatus(player):
+		return SCORE_WIN - depth
+	case wonStatus(opponent(player)):
+		return depth - SCORE_WIN
+	case STATUS_DRAW:
+		return SCORE_DRAW
+	}
+
+	if s.maxDepth > 0 && depth >= s.maxDepth {
+		return s.heuristic(board, player)
+	}
+
+	if turn == player {
+		score := math.MinInt
+		for _, i := range s.candidates(board) {
+			if v := s.minimax(replaceAtIndex(board, turn, i), player, opponent(turn), depth+1, alpha, beta); v > score {
+				score = v
+			}
+			if score > alpha {
+				alpha = score
+			}
+			if alpha >= beta {
+				break
+			}
+		}
+		return score
+	}
+
+	score := math.MaxInt
+	for _, i := range s.candidates(board) {
+		if v := s.minimax(replaceAtIndex(board, turn, i), player, opponent(turn), depth+1, alpha, beta); v < score {
+			score = v
+		}
+		if score < beta {
+			beta = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return score
+}
+
+// heuristic estimates an unfinished board for player. Every line that only
+// one symbol has played in counts for that symbol, four times as much for
+// each extra mark. The estimate stays well clear of the win scores.
+func (s *search) heuristic(board string, player byte) int {
+	g := &Game{Board: board, Size: s.rules.Size, WinLength: s.rules.WinLength}
+	score := 0
+	count := func(line []int) bool {
+		own, other := 0, 0
+		for _, i := range line {
+			switch board[i] {
+			case player:
+				own++
+			case opponent(player):
+				other++
+			}
+		}
+		switch {
+		case other == 0 && own > 0:
+			score += 1 << (2 * (own - 1))
+		case own == 0 && other > 0:
+			score -= 1 << (2 * (other - 1))
+		}
+		return false
+	}
+	g.lines(0, 1, count)
+	g.lines(1, 0, count)
+	g.lines(1, 1, count)
+	g.lines(1, -1, count)
+
+	if limit := SCORE_WIN / 4; score > limit {
+		return limit
+	} else if score < -limit {
+		return -limit
+	}
+	return score
+}
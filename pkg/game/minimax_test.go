@@ -0,0 +1,164 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var classicRules = Rules{Size: 3, WinLength: 3}
+
+func TestGame_bestMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		symbol   byte
+		expected int
+	}{
+		{
+			name:     "full board",
+			board:    "XOXOXOOXO",
+			symbol:   SYMBOL_X,
+			expected: -1,
+		},
+		{
+			name:     "win in a row",
+			board:    "XX-OO----",
+			symbol:   SYMBOL_X,
+			expected: 2,
+		},
+		{
+			name:     "win in a column",
+			board:    "OX-O-X---",
+			symbol:   SYMBOL_O,
+			expected: 6,
+		},
+		{
+			name:     "block a diagonal",
+			board:    "X---X--O-",
+			symbol:   SYMBOL_O,
+			expected: 8,
+		},
+		{
+			name:     "prefer win over block",
+			board:    "XX-OO----",
+			symbol:   SYMBOL_O,
+			expected: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, bestMove(tt.board, tt.symbol, classicRules))
+		})
+	}
+}
+
+// playAll plays every possible sequence of moves for opponent against the
+// engine and fails if the engine ever loses.
+func playAll(t *testing.T, board string, engine, turn byte) {
+	switch evaluate(board, classicRules) {
+	case wonStatus(opponent(engine)):
+		t.Fatalf("engine lost with board %s", board)
+	case STATUS_RUNNING:
+	default:
+		return
+	}
+
+	if turn == engine {
+		playAll(t, replaceAtIndex(board, engine, bestMove(board, engine, classicRules)), engine, opponent(turn))
+		return
+	}
+
+	for _, i := range (&Game{Board: board}).findEmptyCells() {
+		playAll(t, replaceAtIndex(board, turn, i), engine, opponent(turn))
+	}
+}
+
+func TestGame_bestMoveNeverLoses(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine byte
+		first  byte
+	}{
+		{
+			name:   "engine moves first",
+			engine: SYMBOL_O,
+			first:  SYMBOL_O,
+		},
+		{
+			name:   "engine moves second",
+			engine: SYMBOL_O,
+			first:  SYMBOL_X,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playAll(t, "---------", tt.engine, tt.first)
+		})
+	}
+}
+
+func TestGame_bestMoveLargeBoards(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		rules    Rules
+		symbol   byte
+		expected []int
+	}{
+		{
+			name: "4x4 win",
+			board: "XXX-" +
+				"OO--" +
+				"O---" +
+				"----",
+			rules:    Rules{Size: 4, WinLength: 4},
+			symbol:   SYMBOL_X,
+			expected: []int{3},
+		},
+		{
+			name: "4x4 block",
+			board: "OOO-" +
+				"XX--" +
+				"X---" +
+				"----",
+			rules:    Rules{Size: 4, WinLength: 4},
+			symbol:   SYMBOL_X,
+			expected: []int{3},
+		},
+		{
+			name: "5x5 block three",
+			board: "-----" +
+				"OXXX-" +
+				"-O---" +
+				"-----" +
+				"-----",
+			rules:    Rules{Size: 5, WinLength: 4},
+			symbol:   SYMBOL_O,
+			expected: []int{9},
+		},
+		{
+			name:     "15x15 opening",
+			board:    strings.Repeat("-", 225),
+			rules:    Rules{Size: 15, WinLength: 5},
+			symbol:   SYMBOL_X,
+			expected: []int{112},
+		},
+		{
+			name: "15x15 block four",
+			board: strings.Repeat("-", 15*7) +
+				"----OXXXX------" +
+				"-----OOO-------" +
+				strings.Repeat("-", 15*6),
+			rules:    Rules{Size: 15, WinLength: 5},
+			symbol:   SYMBOL_O,
+			expected: []int{15*7 + 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Contains(t, tt.expected, bestMove(tt.board, tt.symbol, tt.rules))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+// Problem codes tell clients why a request failed.
+const (
+	CODE_INVALID_JSON          = "INVALID_JSON"
+	CODE_BODY_TOO_LARGE        = "BODY_TOO_LARGE"
+	CODE_INVALID_LENGTH        = "INVALID_LENGTH"
+	CODE_INVALID_SIZE          = "INVALID_SIZE"
+	CODE_INVALID_CHARACTER     = "INVALID_CHARACTER"
+	CODE_INVALID_SYMBOL        = "INVALID_SYMBOL"
+	CODE_INVALID_CELL          = "INVALID_CELL"
+	CODE_INVALID_GAME_ID       = "INVALID_GAME_ID"
+	CODE_TOO_MANY_MARKS        = "TOO_MANY_MARKS"
+	CODE_SYMBOL_MISMATCH       = "SYMBOL_MISMATCH"
+	CODE_FIRST_PLAYER_MISMATCH = "FIRST_PLAYER_MISMATCH"
+	CODE_ILLEGAL_POSITION      = "ILLEGAL_POSITION"
+	CODE_UNKNOWN_MODE          = "UNKNOWN_MODE"
+	CODE_UNKNOWN_DIFFICULTY    = "UNKNOWN_DIFFICULTY"
+	CODE_UNKNOWN_STRATEGY      = "UNKNOWN_STRATEGY"
+	CODE_CELL_OCCUPIED         = "CELL_OCCUPIED"
+	CODE_MARK_REMOVED          = "MARK_REMOVED"
+	CODE_MULTIPLE_MOVES        = "MULTIPLE_MOVES"
+	CODE_NO_MOVE               = "NO_MOVE"
+	CODE_WRONG_SYMBOL          = "WRONG_SYMBOL"
+	CODE_GAME_FINISHED         = "GAME_FINISHED"
+	CODE_OUT_OF_TURN           = "OUT_OF_TURN"
+	CODE_INVALID_SEAT          = "INVALID_SEAT"
+	CODE_NOT_YOUR_MOVE         = "NOT_YOUR_MOVE"
+	CODE_UNDO_DISABLED         = "UNDO_DISABLED"
+	CODE_NOTHING_TO_UNDO       = "NOTHING_TO_UNDO"
+	CODE_VERSION_MISMATCH      = "VERSION_MISMATCH"
+	CODE_GAME_NOT_FOUND        = "GAME_NOT_FOUND"
+	CODE_GAME_EXPIRED          = "GAME_EXPIRED"
+	CODE_TOO_MANY_GAMES        = "TOO_MANY_GAMES"
+	CODE_INVALID_MESSAGE       = "INVALID_MESSAGE"
+	CODE_INVALID_SNAPSHOT      = "INVALID_SNAPSHOT"
+	CODE_ADMIN_DISABLED        = "ADMIN_DISABLED"
+	CODE_UNAUTHORIZED          = "UNAUTHORIZED"
+	CODE_UNKNOWN_MESSAGE_TYPE  = "UNKNOWN_MESSAGE_TYPE"
+	CODE_STORAGE_FAILURE       = "STORAGE_FAILURE"
+	CODE_SHUTTING_DOWN         = "SHUTTING_DOWN"
+	CODE_INTERNAL_ERROR        = "INTERNAL_ERROR"
+)
+
+var (
+	errInvalidLength    = newAPIError(400, CODE_INVALID_LENGTH, "Invalid input length")
+	errInvalidSize      = newAPIError(400, CODE_INVALID_SIZE, "Invalid board size")
+	errInvalidCharacter = newAPIError(400, CODE_INVALID_CHARACTER, "Invalid board input")
+	errInvalidCell      = newAPIError(400, CODE_INVALID_CELL, "Invalid cell")
+	errCellOccupied     = newAPIError(400, CODE_CELL_OCCUPIED, "Cell is taken")
+	errMarkRemoved      = newAPIError(400, CODE_MARK_REMOVED, "Marks cannot be removed")
+	errMultipleMoves    = newAPIError(400, CODE_MULTIPLE_MOVES, "Only one move at a time")
+	errNoMove           = newAPIError(400, CODE_NO_MOVE, "No move made")
+	errWrongSymbol      = newAPIError(400, CODE_WRONG_SYMBOL, "Wrong symbol")
+	errGameFinished     = newAPIError(409, CODE_GAME_FINISHED, "Game is finished")
+	errOutOfTurn        = newAPIError(409, CODE_OUT_OF_TURN, "Not your turn")
+	errInvalidSeat      = newAPIError(403, CODE_INVALID_SEAT, "Invalid seat token")
+	errShuttingDown     = newAPIError(503, CODE_SHUTTING_DOWN, "Server is shutting down")
+)
+
+// Problem is an error response as described by RFC 7807. Detail is the
+// human-readable reason, Code the machine-readable one, and Cell the board
+// cell the problem was found in, if any. The optional status member is left
+// out: the response status says the same, and a numeric status would break
+// clients that decode every response into a Game.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	Code   string `json:"code"`
+	Cell   *int   `json:"cell,omitempty"`
+}
+
+// apiError is a failed request: the status code, problem code and reason to
+// answer it with, the cell it concerns or -1, and the underlying error if
+// the failure is the server's.
+type apiError struct {
+	status int
+	code   string
+	reason string
+	cell   int
+	err    error
+}
+
+func newAPIError(status int, code, reason string) *apiError {
+	return &apiError{status: status, code: code, reason: reason, cell: -1}
+}
+
+// atCell returns a copy of e that concerns cell.
+func (e *apiError) atCell(cell int) *apiError {
+	c := *e
+	c.cell = cell
+	return &c
+}
+
+func (e *apiError) problem() Problem {
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(e.status),
+		Detail: e.reason,
+		Code:   e.code,
+	}
+	if e.cell >= 0 {
+		cell := e.cell
+		problem.Cell = &cell
+	}
+	return problem
+}
+
+func abortWithError(c *gin.Context, err *apiError) {
+	if err.err != nil {
+		_ = c.Error(err.err)
+	}
+	c.Header("Content-Type", PROBLEM_CONTENT_TYPE)
+	c.AbortWithStatusJSON(err.status, err.problem())
+}
+
+func storageError(err error) *apiError {
+	if errors.Is(err, ErrGameNotFound) {
+		return newAPIError(404, CODE_GAME_NOT_FOUND, "Game not found")
+	}
+	e := newAPIError(500, CODE_STORAGE_FAILURE, "Storage failure")
+	e.err = err
+	return e
+}
+
+// bindError explains why a request body could not be bound: it is not
+// JSON, it is too large, it names an unknown symbol, or its board is
+// missing.
+func bindError(err error) *apiError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &sizeErr):
+		return newAPIError(413, CODE_BODY_TOO_LARGE, "Request body too large")
+	case errors.Is(err, ErrInvalidSymbol):
+		return newAPIError(400, CODE_INVALID_SYMBOL, "Invalid symbol")
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return newAPIError(400, CODE_INVALID_JSON, "Invalid JSON body")
+	}
+	return newAPIError(400, CODE_INVALID_LENGTH, "Invalid input length")
+}
@@ -0,0 +1,368 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	STORAGE_MEMORY = "memory"
+	STORAGE_FILE   = "file"
+)
+
+var (
+	ErrGameNotFound = errors.New("game not found")
+	ErrGameExists   = errors.New("game already exists")
+)
+
+// GameRepository stores games. Implementations hand out copies, so changes to
+// a game only take effect once it is passed to Update.
+type GameRepository interface {
+	Get(id uuid.UUID) (*Game, error)
+	List() ([]*Game, error)
+	Count() (int, error)
+	Create(game *Game) error
+	Update(game *Game) error
+	Delete(id uuid.UUID) error
+}
+
+// Flusher is implemented by repositories whose writes may not have reached
+// durable storage yet. Flush makes sure they have.
+type Flusher interface {
+	Flush() error
+}
+
+// OpenRepository returns the repository for the named storage backend. path
+// is the directory the file backend keeps its games in.
+func OpenRepository(backend, path string) (GameRepository, error) {
+	switch backend {
+	case STORAGE_MEMORY:
+		return NewMemoryRepository(), nil
+	case STORAGE_FILE:
+		return NewFileRepository(path)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", backend)
+}
+
+func (g *Game) clone() *Game {
+	c := *g
+	c.WinningLine = append([]int(nil), g.WinningLine...)
+	c.Moves = append([]Move(nil), g.Moves...)
+	if g.LastMove != nil {
+		last := *g.LastMove
+		c.LastMove = &last
+	}
+	if g.seats != nil {
+		c.seats = make(map[string]string, len(g.seats))
+		for symbol, token := range g.seats {
+			c.seats[symbol] = token
+		}
+	}
+	return &c
+}
+
+// MemoryRepository keeps games in a map. They are lost when the process
+// exits.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	games map[uuid.UUID]*Game
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		games: make(map[uuid.UUID]*Game),
+	}
+}
+
+func (r *MemoryRepository) Get(id uuid.UUID) (*Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	game, ok := r.games[id]
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	return game.clone(), nil
+}
+
+// List returns all games, oldest ID first so the order is stable.
+func (r *MemoryRepository) List() ([]*Game, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	games := make([]*Game, 0, len(r.games))
+	for _, game := range r.games {
+		games = append(games, game.clone())
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].ID.String() < games[j].ID.String()
+	})
+	return games, nil
+}
+
+// Count returns how many games are stored without copying them.
+func (r *MemoryRepository) Count() (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.games), nil
+}
+
+func (r *MemoryRepository) Create(game *Game) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.games[game.ID]; ok {
+		return ErrGameExists
+	}
+	r.games[game.ID] = game.clone()
+	return nil
+}
+
+func (r *MemoryRepository) Update(game *Game) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.games[game.ID]; !ok {
+		return ErrGameNotFound
+	}
+	r.games[game.ID] = game.clone()
+	return nil
+}
+
+func (r *MemoryRepository) Delete(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.games[id]; !ok {
+		return ErrGameNotFound
+	}
+	delete(r.games, id)
+	return nil
+}
+
+// gameRecord is the stored form of a game. It adds the state that is not part
+// of a game's JSON representation.
+type gameRecord struct {
+	*Game
+	Seats map[string]string `json:"seats,omitempty"`
+}
+
+func newGameRecord(game *Game) gameRecord {
+	return gameRecord{
+		Game:  game,
+		Seats: game.seats,
+	}
+}
+
+func (r gameRecord) game() *Game {
+	game := r.Game
+	game.seats = r.Seats
+	if game.LastMove == nil {
+		game.setLastMove()
+	}
+	if game.FirstPlayer == 0 && len(game.Moves) > 0 {
+		game.FirstPlayer = game.Moves[0].Symbol
+	}
+	// Games stored before they were timestamped count from their moves, or
+	// from now if they have none, so they do not all expire at once.
+	if game.UpdatedAt.IsZero() {
+		game.UpdatedAt = time.Now().UTC()
+		if game.LastMove != nil {
+			game.UpdatedAt = game.LastMove.Time
+		}
+	}
+	if game.CreatedAt.IsZero() {
+		game.CreatedAt = game.UpdatedAt
+		if len(game.Moves) > 0 {
+			game.CreatedAt = game.Moves[0].Time
+		}
+	}
+	return game
+}
+
+// load checks a record read from a file or snapshot and returns its game.
+// The moves are checked before anything is derived from them and must replay
+// to the board, since undo relies on them, and the game must then be one the
+// server can serve.
+func (r gameRecord) load() (*Game, error) {
+	if r.Game == nil {
+		return nil, errors.New("empty game")
+	}
+	board := strings.Repeat(string(EMPTY), len(r.Board))
+	for i, move := range r.Moves {
+		if move.Cell < 0 || move.Cell >= len(r.Board) {
+			return nil, fmt.Errorf("move %d: invalid cell %d", i, move.Cell)
+		}
+		if move.Symbol == 0 {
+			return nil, fmt.Errorf("move %d: invalid symbol", i)
+		}
+		if board[move.Cell] != EMPTY {
+			return nil, fmt.Errorf("move %d: cell %d is taken", i, move.Cell)
+		}
+		board = replaceAtIndex(board, byte(move.Symbol), move.Cell)
+	}
+	if board != r.Board {
+		return nil, errors.New("moves do not match the board")
+	}
+	game := r.game()
+	if !game.validateRecord() {
+		return nil, errors.New("invalid game")
+	}
+	return game, nil
+}
+
+// validateRecord checks that a stored game can be served: it has an ID, a
+// board that fits its rules, a known mode, a status that agrees with the
+// board, both symbols if it is played against the server and seats if two
+// people play it.
+func (g *Game) validateRecord() bool {
+	return g.ID != uuid.Nil && g.validateRules() && g.validateLength() &&
+		g.invalidCell() == -1 && g.validateMode() &&
+		(g.Mode != MODE_PVC || (g.ClientSymbol != 0 && g.ServerSymbol != 0 && g.ClientSymbol != g.ServerSymbol)) &&
+		(g.Mode != MODE_PVP || len(g.seats) == 2) &&
+		g.Status == g.boardStatus()
+}
+
+// boardStatus returns the status the game's board has under the game's rules,
+// counting dead draws in games played with EarlyDraw.
+func (g *Game) boardStatus() string {
+	check := &Game{
+		Board:       g.Board,
+		Size:        g.Size,
+		WinLength:   g.WinLength,
+		EarlyDraw:   g.EarlyDraw,
+		FirstPlayer: g.FirstPlayer,
+		Status:      STATUS_RUNNING,
+	}
+	check.updateStatus()
+	return check.Status
+}
+
+// FileRepository keeps every game as a JSON file in a directory and serves
+// reads from memory. Games are loaded when the repository is opened and
+// written through on every change.
+type FileRepository struct {
+	*MemoryRepository
+	dir string
+}
+
+func NewFileRepository(dir string) (*FileRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	r := &FileRepository{
+		MemoryRepository: NewMemoryRepository(),
+		dir:              dir,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		record := gameRecord{Game: &Game{}}
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		game, err := record.load()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		r.games[game.ID] = game
+	}
+
+	return r, nil
+}
+
+func (r *FileRepository) path(id uuid.UUID) string {
+	return filepath.Join(r.dir, id.String()+".json")
+}
+
+// write replaces the game's file atomically, so a crash never leaves a
+// half-written game behind.
+func (r *FileRepository) write(game *Game) error {
+	data, err := json.Marshal(newGameRecord(game))
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(r.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path(game.ID))
+}
+
+// Flush syncs every game file and the directory, so games written before a
+// shutdown survive a crash of the machine that follows it.
+func (r *FileRepository) Flush() error {
+	games, err := r.List()
+	if err != nil {
+		return err
+	}
+	for _, game := range games {
+		if err := syncPath(r.path(game.ID)); err != nil {
+			return err
+		}
+	}
+	return syncPath(r.dir)
+}
+
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+func (r *FileRepository) Create(game *Game) error {
+	if err := r.MemoryRepository.Create(game); err != nil {
+		return err
+	}
+	if err := r.write(game); err != nil {
+		_ = r.MemoryRepository.Delete(game.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *FileRepository) Update(game *Game) error {
+	if _, err := r.MemoryRepository.Get(game.ID); err != nil {
+		return err
+	}
+	if err := r.write(game); err != nil {
+		return err
+	}
+	return r.MemoryRepository.Update(game)
+}
+
+func (r *FileRepository) Delete(id uuid.UUID) error {
+	if err := r.MemoryRepository.Delete(id); err != nil {
+		return err
+	}
+	if err := os.Remove(r.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_CRUD(t *testing.T) {
+	tests := []struct {
+		name string
+		open func(t *testing.T) GameRepository
+	}{
+		{
+			name: "memory",
+			open: func(t *testing.T) GameRepository {
+				return NewMemoryRepository()
+			},
+		},
+		{
+			name: "file",
+			open: func(t *testing.T) GameRepository {
+				repo, err := NewFileRepository(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repo
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.open(t)
+			game := &Game{ID: uuid.New(), Board: "X---O----", Status: STATUS_RUNNING}
+
+			assert.NoError(t, repo.Create(game))
+			assert.ErrorIs(t, repo.Create(game), ErrGameExists)
+
+			stored, err := repo.Get(game.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, game.Board, stored.Board)
+
+			stored.Board = "XX--O----"
+			unchanged, _ := repo.Get(game.ID)
+			assert.Equal(t, "X---O----", unchanged.Board, "changes need Update")
+
+			assert.NoError(t, repo.Update(stored))
+			updated, _ := repo.Get(game.ID)
+			assert.Equal(t, "XX--O----", updated.Board)
+
+			games, err := repo.List()
+			assert.NoError(t, err)
+			assert.Len(t, games, 1)
+			count, err := repo.Count()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			assert.NoError(t, repo.Delete(game.ID))
+			_, err = repo.Get(game.ID)
+			assert.ErrorIs(t, err, ErrGameNotFound)
+			assert.ErrorIs(t, repo.Delete(game.ID), ErrGameNotFound)
+			assert.ErrorIs(t, repo.Update(game), ErrGameNotFound)
+		})
+	}
+}
+
+func TestRepository_FileReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := NewFileRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(WithRepository(repo))
+	game, _, _ := callCreateGame(store.Router, `{"board":"---O-----","strategy":"greedy"}`)
+
+	repo, err = NewFileRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.Get(game.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, game.Board, stored.Board)
+	assert.Equal(t, STRATEGY_GREEDY, stored.Strategy)
+	assert.Equal(t, Symbol(SYMBOL_O), stored.ClientSymbol)
+	assert.Equal(t, Symbol(SYMBOL_X), stored.ServerSymbol)
+
+	store = NewStore(WithRepository(repo))
+	w := httptest.NewRecorder()
+	url := fmt.Sprintf("/api/v1/games/%s", game.ID.String())
+	move := []byte(game.Board)
+	for i := range move {
+		if move[i] == EMPTY {
+			move[i] = SYMBOL_O
+			break
+		}
+	}
+	req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(fmt.Sprintf(`{"board":"%s"}`, move)))
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestRepository_OpenRepository(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		wantErr bool
+	}{
+		{
+			name:    "memory",
+			backend: STORAGE_MEMORY,
+		},
+		{
+			name:    "file",
+			backend: STORAGE_FILE,
+		},
+		{
+			name:    "unknown",
+			backend: "tape",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := OpenRepository(tt.backend, t.TempDir())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, repo)
+		})
+	}
+}
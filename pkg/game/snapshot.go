@@ -0,0 +1,139 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SNAPSHOT_VERSION is the version of the snapshot format written by this
+// server. Snapshots of other versions are refused.
+const SNAPSHOT_VERSION = 1
+
+// snapshot is every game of a store at one point in time, with the state that
+// is not part of a game's JSON representation.
+type snapshot struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Games     []gameRecord `json:"games"`
+}
+
+func writeSnapshot(w io.Writer, games []*Game) error {
+	s := snapshot{
+		Version:   SNAPSHOT_VERSION,
+		CreatedAt: time.Now().UTC(),
+		Games:     make([]gameRecord, 0, len(games)),
+	}
+	for _, game := range games {
+		s.Games = append(s.Games, newGameRecord(game))
+	}
+	return json.NewEncoder(w).Encode(s)
+}
+
+func readSnapshot(r io.Reader) ([]*Game, error) {
+	s := snapshot{}
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	games := make([]*Game, 0, len(s.Games))
+	for i, record := range s.Games {
+		game, err := record.load()
+		if err != nil {
+			return nil, fmt.Errorf("game %d: %w", i, err)
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+// exportGames writes a snapshot of all games to w.
+func (s *Store) exportGames(w io.Writer) error {
+	games, err := s.Games.List()
+	if err != nil {
+		return err
+	}
+	return writeSnapshot(w, games)
+}
+
+// importGames stores games, replacing games with the same IDs, and tells the
+// subscribers of replaced games about their new state. A replaced game gets a
+// version newer than the one it replaces, even if the snapshot is older, so
+// streams pass it on and ETags are never reused for other contents.
+func (s *Store) importGames(games []*Game) error {
+	for _, game := range games {
+		if err := s.importGame(game); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) importGame(game *Game) error {
+	unlock := s.lockGame(game.ID)
+	defer unlock()
+
+	s.attachRandomGenerator(game)
+	existing, err := s.Games.Get(game.ID)
+	switch {
+	case err == nil:
+		if game.Version <= existing.Version {
+			game.Version = existing.Version + 1
+		}
+		err = s.Games.Update(game)
+	case errors.Is(err, ErrGameNotFound):
+		err = s.Games.Create(game)
+	}
+	if err != nil {
+		return err
+	}
+	s.events.publish(game.ID, Event{Type: EVENT_GAME, Game: game.clone()})
+	return nil
+}
+
+// SaveSnapshot writes all games to the file at path, replacing it atomically.
+func (s *Store) SaveSnapshot(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := s.exportGames(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot stores the games of the snapshot at path, replacing games with
+// the same IDs. A missing file is not an error: there is nothing to load
+// before the first shutdown.
+func (s *Store) LoadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	games, err := readSnapshot(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return s.importGames(games)
+}
@@ -0,0 +1,191 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+	store := NewStore(WithConfig(Config{Snapshot: path, Strategy: DEFAULT_STRATEGY, MaxSize: MAX_SIZE}))
+	pvc, _, _ := callCreateGame(store.Router, `{"board":"---------","client_symbol":"O","strategy":"greedy"}`)
+	pvp, w, _ := callCreateGame(store.Router, `{"board":"X--------","mode":"pvp"}`)
+	assert.Equal(t, 201, w.Code)
+
+	assert.NoError(t, store.Flush())
+
+	restored := NewStore()
+	assert.NoError(t, restored.LoadSnapshot(path))
+	games, err := restored.Games.List()
+	assert.NoError(t, err)
+	assert.Len(t, games, 2)
+
+	game, err := restored.Games.Get(pvc.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, pvc.Board, game.Board)
+	assert.Equal(t, pvc.Moves, game.Moves)
+	assert.Equal(t, Symbol(SYMBOL_O), game.ClientSymbol)
+	assert.Equal(t, STRATEGY_GREEDY, game.Strategy)
+
+	game, err = restored.Games.Get(pvp.ID)
+	assert.NoError(t, err)
+	stored, _ := store.Games.Get(pvp.ID)
+	assert.Equal(t, stored.seats, game.seats)
+	assert.Len(t, game.seats, 2)
+
+	assert.NoError(t, restored.LoadSnapshot(path), "loading twice replaces the games")
+	assert.NoError(t, NewStore().LoadSnapshot(filepath.Join(t.TempDir(), "missing.json")))
+}
+
+func TestStore_ImportOlderSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+	store := NewStore()
+	server := httptest.NewServer(store.Router)
+	defer server.Close()
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	assert.NoError(t, store.SaveSnapshot(path))
+	w := httptest.NewRecorder()
+	cell := strings.IndexByte(game.Board, EMPTY)
+	req, _ := http.NewRequest("POST", "/api/v1/games/"+game.ID.String()+"/moves", bytes.NewBufferString(fmt.Sprintf(`{"cell":%d}`, cell)))
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	// Without the restored game's event the read would block, so the client
+	// gives up instead.
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(server.URL + "/api/v1/games/" + game.ID.String() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	_, streamed := readEvent(t, events)
+	assert.Equal(t, 2, streamed.Version)
+
+	assert.NoError(t, store.LoadSnapshot(path))
+	_, streamed = readEvent(t, events)
+	assert.Equal(t, 3, streamed.Version, "a restored game must be newer than the one it replaces")
+	assert.Equal(t, game.Board, streamed.Board)
+}
+
+// snapshotOf returns a snapshot of one running game with an X in its first
+// cell and the given further fields.
+func snapshotOf(fields string) string {
+	return `{"version":1,"games":[{"id":"2a7c9a4e-1d7e-4f3c-9c8e-8f6f0c1b2d3e","board":"X--------","status":"RUNNING",` + fields + `}]}`
+}
+
+func TestStore_SnapshotVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "empty", data: `{"version":1,"games":[]}`},
+		{name: "future version", data: `{"version":2,"games":[]}`, wantErr: true},
+		{name: "no version", data: `{"games":[]}`, wantErr: true},
+		{name: "not json", data: `games`, wantErr: true},
+		{name: "empty game", data: `{"version":1,"games":[null]}`, wantErr: true},
+		{name: "valid game", data: snapshotOf(`"moves":[{"cell":0,"symbol":"X"}],"client_symbol":"X","server_symbol":"O"`)},
+		{name: "move without symbol", data: snapshotOf(`"moves":[{"cell":0}],"client_symbol":"X","server_symbol":"O"`), wantErr: true},
+		{name: "move off the board", data: snapshotOf(`"moves":[{"cell":99,"symbol":"X"}],"client_symbol":"X","server_symbol":"O"`), wantErr: true},
+		{name: "negative cell", data: snapshotOf(`"moves":[{"cell":-1,"symbol":"X"}],"client_symbol":"X","server_symbol":"O"`), wantErr: true},
+		{name: "no symbols", data: snapshotOf(`"moves":[{"cell":0,"symbol":"X"}]`), wantErr: true},
+		{name: "same symbols", data: snapshotOf(`"moves":[{"cell":0,"symbol":"X"}],"client_symbol":"X","server_symbol":"X"`), wantErr: true},
+		{name: "moves of another board", data: snapshotOf(`"moves":[{"cell":4,"symbol":"X"}],"client_symbol":"X","server_symbol":"O"`), wantErr: true},
+		{name: "no moves", data: snapshotOf(`"client_symbol":"X","server_symbol":"O"`), wantErr: true},
+		{name: "cell played twice", data: snapshotOf(`"moves":[{"cell":0,"symbol":"O"},{"cell":0,"symbol":"X"}],"client_symbol":"X","server_symbol":"O"`), wantErr: true},
+		{name: "status of another board", data: strings.Replace(snapshotOf(`"moves":[{"cell":0,"symbol":"X"}],"client_symbol":"X","server_symbol":"O"`), "RUNNING", "X_WON", 1), wantErr: true},
+		{
+			name: "won game",
+			data: `{"version":1,"games":[{"id":"2a7c9a4e-1d7e-4f3c-9c8e-8f6f0c1b2d3e","board":"XXXOO----","status":"X_WON","client_symbol":"X","server_symbol":"O",` +
+				`"moves":[{"cell":0,"symbol":"X"},{"cell":3,"symbol":"O"},{"cell":1,"symbol":"X"},{"cell":4,"symbol":"O"},{"cell":2,"symbol":"X"}]}]}`,
+		},
+		{
+			name: "early draw",
+			data: `{"version":1,"games":[{"id":"2a7c9a4e-1d7e-4f3c-9c8e-8f6f0c1b2d3e","board":"XOXXOOOX-","status":"DRAW","early_draw":true,"client_symbol":"X","server_symbol":"O",` +
+				`"moves":[{"cell":0,"symbol":"X"},{"cell":1,"symbol":"O"},{"cell":2,"symbol":"X"},{"cell":4,"symbol":"O"},{"cell":3,"symbol":"X"},{"cell":5,"symbol":"O"},{"cell":7,"symbol":"X"},{"cell":6,"symbol":"O"}]}]}`,
+		},
+		{
+			name: "early draw without the rule",
+			data: `{"version":1,"games":[{"id":"2a7c9a4e-1d7e-4f3c-9c8e-8f6f0c1b2d3e","board":"XOXXOOOX-","status":"DRAW","client_symbol":"X","server_symbol":"O",` +
+				`"moves":[{"cell":0,"symbol":"X"},{"cell":1,"symbol":"O"},{"cell":2,"symbol":"X"},{"cell":4,"symbol":"O"},{"cell":3,"symbol":"X"},{"cell":5,"symbol":"O"},{"cell":7,"symbol":"X"},{"cell":6,"symbol":"O"}]}]}`,
+			wantErr: true,
+		},
+		{
+			name: "running game that is won",
+			data: `{"version":1,"games":[{"id":"2a7c9a4e-1d7e-4f3c-9c8e-8f6f0c1b2d3e","board":"XXXOO----","status":"RUNNING","client_symbol":"X","server_symbol":"O",` +
+				`"moves":[{"cell":0,"symbol":"X"},{"cell":3,"symbol":"O"},{"cell":1,"symbol":"X"},{"cell":4,"symbol":"O"},{"cell":2,"symbol":"X"}]}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readSnapshot(bytes.NewBufferString(tt.data))
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestRepository_FileInvalidRecord(t *testing.T) {
+	dir := t.TempDir()
+	record := `{"id":"2a7c9a4e-1d7e-4f3c-9c8e-8f6f0c1b2d3e","board":"X--------","status":"RUNNING","moves":[{"cell":0}]}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "2a7c9a4e-1d7e-4f3c-9c8e-8f6f0c1b2d3e.json"), []byte(record), 0o644))
+
+	_, err := NewFileRepository(dir)
+	assert.ErrorContains(t, err, "invalid symbol")
+}
+
+func TestRepository_FileFlush(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := NewFileRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(WithRepository(repo))
+	game, _, _ := callCreateGame(store.Router, `{"board":"---------"}`)
+
+	assert.NoError(t, store.Flush())
+	_, err = os.Stat(filepath.Join(dir, game.ID.String()+".json"))
+	assert.NoError(t, err)
+}
+
+func TestStore_SeededAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+	config := Config{Seed: 7, Strategy: STRATEGY_RANDOM, MaxSize: MAX_SIZE}
+	store := NewStore(WithConfig(config))
+	created, w, _ := callCreateGame(store.Router, `{"board":"X------------------------","size":5}`)
+	assert.Equal(t, 201, w.Code)
+	assert.NoError(t, store.SaveSnapshot(path))
+
+	// play restarts the server from the snapshot, makes the same moves and
+	// returns the board they end with.
+	play := func() string {
+		restarted := NewStore(WithConfig(config))
+		assert.NoError(t, restarted.LoadSnapshot(path))
+
+		game := &Game{}
+		for _, cell := range []string{"24", "23"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/games/"+created.ID.String()+"/moves", bytes.NewBufferString(`{"cell":`+cell+`}`))
+			restarted.Router.ServeHTTP(w, req)
+			assert.Equal(t, 200, w.Code, w.Body.String())
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), game))
+		}
+		return game.Board
+	}
+
+	assert.Equal(t, play(), play(), "seeded servers must play the same moves after a restart")
+}
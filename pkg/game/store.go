@@ -1,77 +1,423 @@
 package game
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// SEAT_TOKEN_HEADER carries the seat token of the player making a request to
+// a human-vs-human game.
+const SEAT_TOKEN_HEADER = "X-Seat-Token"
+
+// Store serves the game API. Requests that change a game hold that game's
+// lock from reading it to storing it, so they are applied one at a time.
 type Store struct {
-	Games           map[uuid.UUID]*Game
+	Games           GameRepository
 	randomGenerator *rand.Rand
 	Router          *gin.Engine
+
+	events   *broker
+	randomMu sync.Mutex
+	createMu sync.Mutex
+	locksMu  sync.Mutex
+	locks    map[uuid.UUID]*gameLock
+
+	// done is closed when the store starts shutting down, which ends the
+	// event streams and WebSocket connections counted by streams.
+	done      chan struct{}
+	streamsMu sync.Mutex
+	draining  bool
+	streams   sync.WaitGroup
+
+	baseURL         string
+	defaultStrategy string
+	maxSize         int
+	maxGames        int
+	maxBodyBytes    int64
+	snapshot        string
+	adminToken      string
+
+	// now is the clock games are timestamped and expired by.
+	now             func() time.Time
+	idleTTL         time.Duration
+	finishedTTL     time.Duration
+	goneTTL         time.Duration
+	janitorInterval time.Duration
+	goneMu          sync.Mutex
+	gone            map[uuid.UUID]time.Time
+}
+
+// StoreOption changes how NewStore sets up a store.
+type StoreOption func(*Store)
+
+// WithRepository keeps the store's games in repo instead of in memory.
+func WithRepository(repo GameRepository) StoreOption {
+	return func(s *Store) {
+		s.Games = repo
+	}
 }
 
-func NewStore() *Store {
+// WithConfig applies the settings of c that concern the API: the base URL,
+// the default strategy, the random seed, the limits, the snapshot, the admin
+// token and the expiry of games.
+func WithConfig(c Config) StoreOption {
+	return func(s *Store) {
+		s.baseURL = strings.TrimSuffix(c.BaseURL, "/")
+		s.defaultStrategy = c.Strategy
+		s.maxSize = c.MaxSize
+		s.maxGames = c.MaxGames
+		s.maxBodyBytes = c.MaxBodyBytes
+		s.snapshot = c.Snapshot
+		s.adminToken = c.AdminToken
+		s.idleTTL = time.Duration(c.IdleTTL)
+		s.finishedTTL = time.Duration(c.FinishedTTL)
+		s.goneTTL = time.Duration(c.GoneTTL)
+		s.janitorInterval = time.Duration(c.JanitorInterval)
+		if c.Seed != 0 {
+			s.randomGenerator = rand.New(rand.NewSource(c.Seed))
+		}
+	}
+}
+
+func NewStore(options ...StoreOption) *Store {
 	gs := &Store{
-		Games:           make(map[uuid.UUID]*Game),
+		Games:           NewMemoryRepository(),
 		randomGenerator: rand.New(rand.NewSource(time.Now().UnixNano())),
 		Router:          gin.Default(),
+		events:          newBroker(),
+		locks:           make(map[uuid.UUID]*gameLock),
+		done:            make(chan struct{}),
+		defaultStrategy: DEFAULT_STRATEGY,
+		maxSize:         MAX_SIZE,
+		now:             time.Now,
+		gone:            make(map[uuid.UUID]time.Time),
+	}
+
+	for _, option := range options {
+		option(gs)
+	}
+
+	if (gs.idleTTL > 0 || gs.finishedTTL > 0) && gs.janitorInterval > 0 {
+		go gs.runJanitor()
+	}
+
+	api := gs.Router.Group("api/v1")
+	if gs.maxBodyBytes > 0 {
+		api.Use(limitBody(gs.maxBodyBytes))
 	}
 
-	gs.Router.GET("api/v1/games", gs.GetAllGames)
-	gs.Router.GET("api/v1/games/:game_id", gs.GetSingleGame)
-	gs.Router.POST("api/v1/games", gs.CreateGame)
-	gs.Router.PUT("api/v1/games/:game_id", gs.MakeMove)
-	gs.Router.DELETE("api/v1/games/:game_id", gs.DeleteGame)
+	api.GET("games", gs.GetAllGames)
+	api.GET("games/:game_id", gs.GetSingleGame)
+	api.GET("games/:game_id/hint", gs.GetHint)
+	api.GET("games/:game_id/moves", gs.GetMoves)
+	api.GET("games/:game_id/events", gs.StreamEvents)
+	api.POST("games", gs.CreateGame)
+	api.POST("games/:game_id/moves", gs.PlayMove)
+	api.PUT("games/:game_id", gs.MakeMove)
+	api.POST("games/:game_id/undo", gs.UndoMove)
+	api.DELETE("games/:game_id", gs.DeleteGame)
+	api.POST("analysis", gs.AnalyzeBoard)
+	api.GET("ws", gs.ServeWebSocket)
+
+	// Snapshots hold every game, so their size is not limited.
+	admin := gs.Router.Group("api/v1/admin", gs.requireAdmin)
+	admin.GET("snapshot", gs.ExportGames)
+	admin.POST("snapshot", gs.ImportGames)
 
 	return gs
 }
 
-func (s *Store) GetAllGames(c *gin.Context) {
-	games := make([]*Game, 0)
+// newRandomGenerator gives each game its own generator, seeded from the
+// store's, since a *rand.Rand must not be shared between goroutines.
+func (s *Store) newRandomGenerator() *rand.Rand {
+	s.randomMu.Lock()
+	defer s.randomMu.Unlock()
+	return rand.New(rand.NewSource(s.randomGenerator.Int63()))
+}
+
+// attachRandomGenerator gives game a generator from the store's if it has
+// none because it was loaded from a file or imported from a snapshot, so a
+// seeded server plays the same moves after a restart.
+func (s *Store) attachRandomGenerator(game *Game) {
+	if game.randomGenerator == nil {
+		game.randomGenerator = s.newRandomGenerator()
+	}
+}
+
+// gameLock is the lock of one game, with the number of requests holding or
+// waiting for it.
+type gameLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockGame locks the game with the given ID and returns the function that
+// unlocks it. Locks are dropped once no request holds or waits for them, so
+// requests for unknown or deleted games leave nothing behind.
+func (s *Store) lockGame(id uuid.UUID) func() {
+	s.locksMu.Lock()
+	lock, ok := s.locks[id]
+	if !ok {
+		lock = &gameLock{}
+		s.locks[id] = lock
+	}
+	lock.refs++
+	s.locksMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		s.locksMu.Lock()
+		defer s.locksMu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.locks, id)
+		}
+	}
+}
+
+// openStream counts a new event stream or WebSocket connection, which must
+// call closeStream when it ends. It reports false once the store is shutting
+// down.
+func (s *Store) openStream() bool {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	if s.draining {
+		return false
+	}
+	s.streams.Add(1)
+	return true
+}
 
-	for _, g := range s.Games {
-		games = append(games, g)
+func (s *Store) closeStream() {
+	s.streams.Done()
+}
+
+// Drain ends all event streams and WebSocket connections, refuses new ones
+// and waits for their handlers to return or ctx to be done. Requests that
+// are not streams are left to http.Server.Shutdown.
+func (s *Store) Drain(ctx context.Context) error {
+	s.streamsMu.Lock()
+	if !s.draining {
+		s.draining = true
+		close(s.done)
+	}
+	s.streamsMu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		s.streams.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush saves the games before the server exits: it flushes the repository
+// if it buffers writes and writes the configured snapshot, if any.
+func (s *Store) Flush() error {
+	if flusher, ok := s.Games.(Flusher); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.snapshot != "" {
+		return s.SaveSnapshot(s.snapshot)
+	}
+	return nil
+}
+
+func (s *Store) GetAllGames(c *gin.Context) {
+	games, err := s.Games.List()
+	if err != nil {
+		abortWithError(c, storageError(err))
+		return
 	}
 
 	c.JSON(http.StatusOK, games)
 }
 
 func (s *Store) CreateGame(c *gin.Context) {
-	newGame := Game{
-		randomGenerator: s.randomGenerator,
-	}
+	newGame := Game{}
 
 	if err := c.ShouldBindJSON(&newGame); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"reason": "Invalid input length"})
+		abortWithError(c, bindError(err))
+		return
+	}
+
+	if err := s.startGame(&newGame); err != nil {
+		abortWithError(c, err)
 		return
 	}
 
+	c.Header("Location", s.gameURL(c, newGame.ID))
+	c.Header("ETag", newGame.etag())
+
+	c.JSON(201, createdGame{Game: &newGame, Seats: newGame.seats})
+}
+
+// limitBody refuses request bodies longer than limit bytes.
+func limitBody(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// gameURL returns the URL of the game with the given ID: below the configured
+// base URL, or else below the address the client reached, as reported by the
+// X-Forwarded-Proto and X-Forwarded-Host headers of proxies or by the Host
+// header.
+func (s *Store) gameURL(c *gin.Context, id uuid.UUID) string {
+	base := s.baseURL
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		if proto := firstHeaderValue(c, "X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		host := c.Request.Host
+		if forwarded := firstHeaderValue(c, "X-Forwarded-Host"); forwarded != "" {
+			host = forwarded
+		}
+		base = scheme + "://" + host
+	}
+	return fmt.Sprintf("%s/api/v1/games/%s", base, id.String())
+}
+
+// firstHeaderValue returns the first of the comma-separated values of a
+// header, which a chain of proxies may have appended to.
+func firstHeaderValue(c *gin.Context, name string) string {
+	value, _, _ := strings.Cut(c.GetHeader(name), ",")
+	return strings.TrimSpace(value)
+}
+
+// startGame validates a game requested by a client, makes the server's
+// opening move if it plays one and stores the game.
+func (s *Store) startGame(newGame *Game) *apiError {
+	newGame.randomGenerator = s.newRandomGenerator()
 	newGame.Board = strings.ToUpper(newGame.Board)
 
-	if !newGame.validateFirstInput() || !newGame.validateBoard() {
-		c.AbortWithStatusJSON(400, gin.H{"reason": "Invalid board input"})
-		return
+	if !newGame.validateRules() || newGame.Size > s.maxSize {
+		return errInvalidSize
+	}
+
+	if !newGame.validateLength() {
+		return errInvalidLength
+	}
+
+	if cell := newGame.invalidCell(); cell != -1 {
+		return errInvalidCharacter.atCell(cell)
+	}
+
+	if !newGame.validateFirstInput() {
+		return newAPIError(400, CODE_TOO_MANY_MARKS, "Only the opening move may be on the board")
+	}
+
+	if !newGame.validateMode() {
+		return newAPIError(400, CODE_UNKNOWN_MODE, "Unknown mode")
+	}
+
+	if newGame.Mode == MODE_PVC {
+		if newGame.Strategy == "" {
+			newGame.Strategy = s.defaultStrategy
+		}
+
+		if !newGame.validateDifficulty() {
+			return newAPIError(400, CODE_UNKNOWN_DIFFICULTY, "Unknown difficulty")
+		}
+
+		if !newGame.setStrategy() {
+			return newAPIError(400, CODE_UNKNOWN_STRATEGY, "Unknown strategy")
+		}
+	} else {
+		newGame.Strategy = ""
+		newGame.Difficulty = ""
+		if err := newGame.setSeats(); err != nil {
+			e := newAPIError(500, CODE_INTERNAL_ERROR, "Cannot create seats")
+			e.err = err
+			return e
+		}
 	}
 
 	newGame.ID = uuid.New()
+	newGame.CreatedAt = s.now().UTC()
+	newGame.UpdatedAt = newGame.CreatedAt
 	newGame.Status = STATUS_RUNNING
+	newGame.Version = 1
+	newGame.Moves = make([]Move, 0)
+	newGame.WinningLine = nil
+	newGame.LastMove = nil
+
+	if newGame.Mode == MODE_PVC {
+		if !newGame.setServerSymbol() {
+			return newAPIError(400, CODE_SYMBOL_MISMATCH, "Client symbol does not match the board")
+		}
+	} else {
+		newGame.ClientSymbol = 0
+		newGame.ServerSymbol = 0
+	}
+	if !newGame.setFirstPlayer() {
+		return newAPIError(400, CODE_FIRST_PLAYER_MISMATCH, "First player does not match the board")
+	}
+
+	if !newGame.validatePosition() {
+		return newAPIError(400, CODE_ILLEGAL_POSITION, "Illegal board position")
+	}
 
-	s.Games[newGame.ID] = &newGame
+	if cell := strings.IndexAny(newGame.Board, "XO"); cell != -1 {
+		symbol := newGame.Board[cell]
+		newGame.Board = replaceAtIndex(newGame.Board, EMPTY, cell)
+		newGame.play(cell, symbol, ACTOR_CLIENT)
+	}
+	if newGame.Mode == MODE_PVC && newGame.turn() == byte(newGame.ServerSymbol) {
+		newGame.makeCounterMove()
+	}
 
-	newGame.setServerSymbol()
-	newGame.makeCounterMove()
+	newGame.predictOutcome()
+
+	// Count and create under one lock so concurrent requests cannot both
+	// take the last free place.
+	s.createMu.Lock()
+	defer s.createMu.Unlock()
+	if s.maxGames > 0 {
+		count, err := s.Games.Count()
+		if err != nil {
+			return storageError(err)
+		}
+		if count >= s.maxGames {
+			return newAPIError(503, CODE_TOO_MANY_GAMES, "Too many games")
+		}
+	}
 
-	location := fmt.Sprintf("http://127.0.0.1:8080/api/v1/games/%s", newGame.ID.String())
-	c.Header("Location", location)
+	if err := s.Games.Create(newGame); err != nil {
+		return storageError(err)
+	}
+	return nil
+}
 
-	c.JSON(201, newGame)
+// createdGame is the response to creating a game. It is the only response
+// that carries the seat tokens of a human-vs-human game.
+type createdGame struct {
+	*Game
+	Seats map[string]string `json:"seats,omitempty"`
 }
 
 func (s *Store) GetSingleGame(c *gin.Context) {
@@ -80,72 +426,400 @@ func (s *Store) GetSingleGame(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", game.etag())
 	c.JSON(200, game)
 }
 
-func (s *Store) DeleteGame(c *gin.Context) {
+func (s *Store) GetMoves(c *gin.Context) {
 	game := s.getGameFromContext(c)
 	if game == nil {
 		return
 	}
 
-	delete(s.Games, game.ID)
+	c.JSON(200, game.Moves)
+}
+
+// StreamEvents streams the game as Server-Sent Events: its current state
+// first, then its new state after every change, until the game is deleted or
+// the client goes away.
+func (s *Store) StreamEvents(c *gin.Context) {
+	gameID, ok := s.gameIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if !s.openStream() {
+		abortWithError(c, errShuttingDown)
+		return
+	}
+	defer s.closeStream()
+
+	// Subscribe before reading the game so no change slips in between.
+	events, cancel := s.events.subscribe(gameID)
+	defer cancel()
+
+	game, err := s.Games.Get(gameID)
+	if err != nil {
+		abortWithError(c, s.gameError(gameID, err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.SSEvent(EVENT_GAME, game)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-events:
+			if event.Type == EVENT_DELETED {
+				c.SSEvent(EVENT_DELETED, gin.H{"id": event.Game.ID})
+				return false
+			}
+			if event.Game.Version > game.Version {
+				game = event.Game
+				c.SSEvent(EVENT_GAME, game)
+			}
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		case <-s.done:
+			return false
+		}
+	})
+}
+
+func (s *Store) GetHint(c *gin.Context) {
+	game := s.getGameFromContext(c)
+	if game == nil {
+		return
+	}
+
+	if game.Status != STATUS_RUNNING {
+		abortWithError(c, errGameFinished)
+		return
+	}
+
+	symbol := byte(game.ClientSymbol)
+	if game.Mode == MODE_PVP {
+		symbol = game.turn()
+	}
+	c.JSON(200, game.hint(symbol))
+}
+
+func (s *Store) DeleteGame(c *gin.Context) {
+	game, unlock := s.lockGameFromContext(c)
+	if game == nil {
+		return
+	}
+	defer unlock()
+
+	if !checkIfMatch(c, game) {
+		return
+	}
+
+	if err := s.Games.Delete(game.ID); err != nil {
+		abortWithError(c, storageError(err))
+		return
+	}
+	s.events.publish(game.ID, Event{Type: EVENT_DELETED, Game: game})
 
 	c.JSON(200, gin.H{"description": "Game successfully deleted"})
 }
 
-func (s *Store) getGameFromContext(c *gin.Context) *Game {
+func (s *Store) gameIDFromContext(c *gin.Context) (uuid.UUID, bool) {
 	id := c.Param("game_id")
 	gameID, err := uuid.Parse(id)
 	if err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"reason": "UUID cannot be parsed"})
-		return nil
+		abortWithError(c, newAPIError(400, CODE_INVALID_GAME_ID, "UUID cannot be parsed"))
+		return uuid.Nil, false
 	}
+	return gameID, true
+}
 
-	game, ok := s.Games[gameID]
+func (s *Store) getGameFromContext(c *gin.Context) *Game {
+	gameID, ok := s.gameIDFromContext(c)
 	if !ok {
-		c.AbortWithStatusJSON(404, gin.H{"reason": "Game not found"})
+		return nil
+	}
+
+	game, err := s.Games.Get(gameID)
+	if err != nil {
+		abortWithError(c, s.gameError(gameID, err))
 		return nil
 	}
 
 	return game
 }
 
+// lockGameFromContext is getGameFromContext for requests that change the
+// game. The game stays locked until the returned function is called; it is
+// already unlocked if no game is returned.
+func (s *Store) lockGameFromContext(c *gin.Context) (*Game, func()) {
+	gameID, ok := s.gameIDFromContext(c)
+	if !ok {
+		return nil, nil
+	}
+
+	unlock := s.lockGame(gameID)
+	game, err := s.Games.Get(gameID)
+	if err != nil {
+		unlock()
+		abortWithError(c, s.gameError(gameID, err))
+		return nil, nil
+	}
+	s.attachRandomGenerator(game)
+
+	return game, unlock
+}
+
+// seatFromContext returns the symbol played by the seat token of the request
+// and aborts it with 403 if the token is not a seat of game.
+func seatFromContext(c *gin.Context, game *Game) (byte, bool) {
+	symbol := game.seat(c.GetHeader(SEAT_TOKEN_HEADER))
+	if symbol == 0 {
+		abortWithError(c, errInvalidSeat)
+		return 0, false
+	}
+	return symbol, true
+}
+
+// playerOf returns the symbol that the holder of seat moves with in game:
+// the client's symbol in games against the server, and the seat's own
+// symbol in two-player games, where it must also be that seat's turn.
+func playerOf(game *Game, seat string) (byte, *apiError) {
+	if game.Mode != MODE_PVP {
+		return byte(game.ClientSymbol), nil
+	}
+
+	symbol := game.seat(seat)
+	if symbol == 0 {
+		return 0, errInvalidSeat
+	}
+	if symbol != game.turn() {
+		return 0, errOutOfTurn
+	}
+	return symbol, nil
+}
+
+// updateGame stores the changes made to game as its next version and tells
+// the game's subscribers about it.
+func (s *Store) updateGame(game *Game) *apiError {
+	game.predictOutcome()
+	game.Version++
+	game.UpdatedAt = s.now().UTC()
+	if err := s.Games.Update(game); err != nil {
+		return storageError(err)
+	}
+	s.events.publish(game.ID, Event{Type: EVENT_GAME, Game: game.clone()})
+	return nil
+}
+
+// saveGame stores the changes made to game as its next version and responds
+// with it.
+func (s *Store) saveGame(c *gin.Context, game *Game) {
+	if err := s.updateGame(game); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.Header("ETag", game.etag())
+	c.JSON(200, game)
+}
+
+func (g *Game) etag() string {
+	return fmt.Sprintf(`"%d"`, g.Version)
+}
+
+// checkIfMatch lets a request through if it has no If-Match header or the
+// header names the game's current version, and aborts it with 412 otherwise.
+func checkIfMatch(c *gin.Context, game *Game) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	etag := game.etag()
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	abortWithError(c, newAPIError(412, CODE_VERSION_MISMATCH, "Game has been changed"))
+	return false
+}
+
+// checkRunning lets moves through while the game is running and aborts them
+// with 409 once it is finished, since finished games are final except for
+// undo.
+func checkRunning(c *gin.Context, game *Game) bool {
+	if game.Status != STATUS_RUNNING {
+		abortWithError(c, errGameFinished)
+		return false
+	}
+	return true
+}
+
 func (s *Store) MakeMove(c *gin.Context) {
-	game := s.getGameFromContext(c)
+	game, unlock := s.lockGameFromContext(c)
 	if game == nil {
 		return
 	}
+	defer unlock()
+
+	if !checkIfMatch(c, game) {
+		return
+	}
+
+	if !checkRunning(c, game) {
+		return
+	}
 
 	newGame := &Game{}
 
 	if err := c.ShouldBindJSON(newGame); err != nil {
-		c.AbortWithStatusJSON(400, gin.H{"reason": "Invalid input length"})
+		abortWithError(c, bindError(err))
 		return
 	}
 
 	newGame.Board = strings.ToUpper(newGame.Board)
 
-	if !newGame.validateBoard() || !game.validateMove((newGame)) {
-		c.AbortWithStatusJSON(400, gin.H{"reason": "Invalid board input"})
+	if len(newGame.Board) != len(game.Board) {
+		abortWithError(c, errInvalidLength)
 		return
 	}
 
-	game.Board = newGame.Board
+	symbol, err := playerOf(game, c.GetHeader(SEAT_TOKEN_HEADER))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
 
-	game.updateStatus()
-	if game.Status != STATUS_RUNNING {
-		c.JSON(200, game)
+	if err := game.checkMove(newGame, symbol); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	game.move(game.changedCell(newGame), symbol)
+	s.saveGame(c, game)
+}
+
+// PlayMove plays the cell named in the request, an alternative to MakeMove
+// that spares the client from sending the whole board.
+func (s *Store) PlayMove(c *gin.Context) {
+	game, unlock := s.lockGameFromContext(c)
+	if game == nil {
 		return
 	}
+	defer unlock()
 
-	game.makeCounterMove()
+	if !checkIfMatch(c, game) {
+		return
+	}
 
-	game.updateStatus()
-	if game.Status != STATUS_RUNNING {
-		c.JSON(200, game)
+	if !checkRunning(c, game) {
 		return
 	}
 
-	c.JSON(200, game)
+	request := MoveRequest{}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithError(c, bindError(err))
+		return
+	}
+
+	symbol, err := playerOf(game, c.GetHeader(SEAT_TOKEN_HEADER))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	cell := request.cell(game.rules())
+	if err := validateCell(game, cell, symbol); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	game.move(cell, symbol)
+	s.saveGame(c, game)
+}
+
+// validateCell checks that symbol may play cell of game's board.
+func validateCell(game *Game, cell int, symbol byte) *apiError {
+	if cell < 0 || cell >= len(game.Board) {
+		return errInvalidCell
+	}
+	if game.Board[cell] != EMPTY {
+		return errCellOccupied.atCell(cell)
+	}
+	return game.checkMove(&Game{Board: replaceAtIndex(game.Board, symbol, cell)}, symbol)
+}
+
+func (s *Store) UndoMove(c *gin.Context) {
+	game, unlock := s.lockGameFromContext(c)
+	if game == nil {
+		return
+	}
+	defer unlock()
+
+	if !checkIfMatch(c, game) {
+		return
+	}
+
+	// Undo is the exception to the finished-game rule: it may take back the
+	// move that ended the game, which reopens it.
+	if game.UndoDisabled {
+		abortWithError(c, newAPIError(403, CODE_UNDO_DISABLED, "Undo is disabled for this game"))
+		return
+	}
+
+	if game.Mode == MODE_PVP {
+		symbol, ok := seatFromContext(c, game)
+		if !ok {
+			return
+		}
+
+		if len(game.Moves) > 0 && game.Moves[len(game.Moves)-1].Symbol != Symbol(symbol) {
+			abortWithError(c, newAPIError(409, CODE_NOT_YOUR_MOVE, "Not your move to undo"))
+			return
+		}
+	}
+
+	if !game.undo() {
+		abortWithError(c, newAPIError(400, CODE_NOTHING_TO_UNDO, "Nothing to undo"))
+		return
+	}
+
+	s.saveGame(c, game)
+}
+
+func (s *Store) AnalyzeBoard(c *gin.Context) {
+	request := AnalysisRequest{}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithError(c, bindError(err))
+		return
+	}
+
+	request.Board = strings.ToUpper(request.Board)
+
+	position := &Game{Board: request.Board, Size: request.Size, WinLength: request.WinLength}
+
+	if !position.validateRules() || position.Size > s.maxSize {
+		abortWithError(c, errInvalidSize)
+		return
+	}
+
+	if !position.validateLength() {
+		abortWithError(c, errInvalidLength)
+		return
+	}
+
+	if cell := position.invalidCell(); cell != -1 {
+		abortWithError(c, errInvalidCharacter.atCell(cell))
+		return
+	}
+
+	c.JSON(200, analyze(request.Board, byte(request.FirstPlayer), position.rules()))
 }
@@ -7,7 +7,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,7 +19,7 @@ import (
 
 func callCreateGame(router *gin.Engine, input string) (*Game, *httptest.ResponseRecorder, error) {
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/v1/games", bytes.NewBufferString(input))
+	req, _ := http.NewRequest("POST", "http://127.0.0.1:8080/api/v1/games", bytes.NewBufferString(input))
 
 	router.ServeHTTP(w, req)
 
@@ -65,6 +68,18 @@ func callGetSingleGame(router *gin.Engine, id string) (*Game, *httptest.Response
 	return game, w, nil
 }
 
+func setBoard(t *testing.T, store *Store, id uuid.UUID, board, status string) {
+	game, err := store.Games.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.Board = board
+	game.Status = status
+	if err := store.Games.Update(game); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestGame_CreateandGetGames(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -172,13 +187,13 @@ func TestStore_DeleteGames(t *testing.T) {
 			name:     "invalid UUID",
 			url:      "http://127.0.0.1:8080/api/v1/games/qweqwe",
 			wantCode: 400,
-			wantBody: `{"reason":"UUID cannot be parsed"}`,
+			wantBody: `{"type":"about:blank","title":"Bad Request","detail":"UUID cannot be parsed","code":"INVALID_GAME_ID"}`,
 		},
 		{
 			name:     "Wrong ID",
 			url:      "http://127.0.0.1:8080/api/v1/games/00000000-0000-0000-0000-000000000000",
 			wantCode: 404,
-			wantBody: `{"reason":"Game not found"}`,
+			wantBody: `{"type":"about:blank","title":"Not Found","detail":"Game not found","code":"GAME_NOT_FOUND"}`,
 		},
 	}
 
@@ -211,22 +226,22 @@ func TestStore_MakeMove(t *testing.T) {
 	}{
 		{
 			name:       "X wins",
-			board:      "OXXOXOXO-",
-			move:       `{"board":"OXXOXOXOX"}`,
+			board:      "XX-OO----",
+			move:       `{"board":"XXXOO----"}`,
 			wantCode:   200,
 			wantStatus: STATUS_X_WON,
 		},
 		{
 			name:       "O wins",
-			board:      "XOOXOXOO-",
-			move:       `{"board":"XOOXOXOOX"}`,
+			board:      "X--OO---X",
+			move:       `{"board":"XX-OO---X"}`,
 			wantCode:   200,
 			wantStatus: STATUS_O_WON,
 		},
 		{
 			name:       "Draw",
-			board:      "OXOXOXXO-",
-			move:       `{"board":"OXOXOXXOX"}`,
+			board:      "-XOOXXXOO",
+			move:       `{"board":"XXOOXXXOO"}`,
 			wantCode:   200,
 			wantStatus: STATUS_DRAW,
 		},
@@ -255,13 +270,12 @@ func TestStore_MakeMove(t *testing.T) {
 
 	store := NewStore()
 
-	game, _, _ := callCreateGame(store.Router, `{"board":"---------"}`)
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
 	url := fmt.Sprintf("http://127.0.0.1:8080/api/v1/games/%s", game.ID.String())
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			store.Games[game.ID].Board = tt.board
-			store.Games[game.ID].Status = STATUS_RUNNING
+			setBoard(t, store, game.ID, tt.board, STATUS_RUNNING)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(tt.move))
@@ -281,3 +295,1208 @@ func TestStore_MakeMove(t *testing.T) {
 		})
 	}
 }
+
+func TestStore_CreateGameStrategy(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantCode       int
+		wantStrategy   string
+		wantDifficulty string
+	}{
+		{
+			name:           "default",
+			input:          `{"board":"---------"}`,
+			wantCode:       201,
+			wantStrategy:   DEFAULT_STRATEGY,
+			wantDifficulty: DEFAULT_DIFFICULTY,
+		},
+		{
+			name:           "random",
+			input:          `{"board":"---------","strategy":"random"}`,
+			wantCode:       201,
+			wantStrategy:   STRATEGY_RANDOM,
+			wantDifficulty: DEFAULT_DIFFICULTY,
+		},
+		{
+			name:           "greedy",
+			input:          `{"board":"X--------","strategy":"greedy"}`,
+			wantCode:       201,
+			wantStrategy:   STRATEGY_GREEDY,
+			wantDifficulty: DEFAULT_DIFFICULTY,
+		},
+		{
+			name:           "easy",
+			input:          `{"board":"X--------","difficulty":"easy"}`,
+			wantCode:       201,
+			wantStrategy:   DEFAULT_STRATEGY,
+			wantDifficulty: DIFFICULTY_EASY,
+		},
+		{
+			name:           "unknown strategy",
+			input:          `{"board":"---------","strategy":"psychic"}`,
+			wantCode:       400,
+			wantStrategy:   "",
+			wantDifficulty: "",
+		},
+		{
+			name:           "unknown difficulty",
+			input:          `{"board":"---------","difficulty":"nightmare"}`,
+			wantCode:       400,
+			wantStrategy:   "",
+			wantDifficulty: "",
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, w, err := callCreateGame(store.Router, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantStrategy, game.Strategy)
+			assert.Equal(t, tt.wantDifficulty, game.Difficulty)
+		})
+	}
+}
+
+func TestStore_GetHint(t *testing.T) {
+	tests := []struct {
+		name     string
+		board    string
+		status   string
+		wantCode int
+		wantCell int
+	}{
+		{
+			name:     "running",
+			board:    "XX-OO----",
+			status:   STATUS_RUNNING,
+			wantCode: 200,
+			wantCell: 2,
+		},
+		{
+			name:     "finished",
+			board:    "XXXOO----",
+			status:   STATUS_X_WON,
+			wantCode: 409,
+			wantCell: 0,
+		},
+	}
+
+	store := NewStore()
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	url := fmt.Sprintf("/api/v1/games/%s/hint", game.ID.String())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setBoard(t, store, game.ID, tt.board, tt.status)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+
+			store.Router.ServeHTTP(w, req)
+
+			hint := &Hint{}
+			if err := json.Unmarshal(w.Body.Bytes(), hint); err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantCell, hint.Cell)
+		})
+	}
+}
+
+func TestStore_AnalyzeBoard(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantCode  int
+		wantLegal bool
+		wantTurn  string
+		wantValue string
+		wantErr   string
+	}{
+		{
+			name:      "running",
+			input:     `{"board":"x---o----"}`,
+			wantCode:  200,
+			wantLegal: true,
+			wantTurn:  "X",
+			wantValue: STATUS_DRAW,
+		},
+		{
+			name:      "O first",
+			input:     `{"board":"----O----","first_player":"O"}`,
+			wantCode:  200,
+			wantLegal: true,
+			wantTurn:  "X",
+			wantValue: STATUS_DRAW,
+		},
+		{
+			name:      "O first by the count",
+			input:     `{"board":"----O----"}`,
+			wantCode:  200,
+			wantLegal: true,
+			wantTurn:  "X",
+			wantValue: STATUS_DRAW,
+		},
+		{
+			name:      "illegal",
+			input:     `{"board":"----O----","first_player":"X"}`,
+			wantCode:  200,
+			wantLegal: false,
+		},
+		{
+			name:     "invalid first player",
+			input:    `{"board":"----O----","first_player":"Z"}`,
+			wantCode: 400,
+			wantErr:  CODE_INVALID_SYMBOL,
+		},
+		{
+			name:     "invalid board input",
+			input:    `{"board":"----a----"}`,
+			wantCode: 400,
+		},
+		{
+			name:     "invalid input length",
+			input:    `{"board":"----"}`,
+			wantCode: 400,
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/analysis", bytes.NewBufferString(tt.input))
+
+			store.Router.ServeHTTP(w, req)
+
+			analysis := &Analysis{}
+			if err := json.Unmarshal(w.Body.Bytes(), analysis); err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantLegal, analysis.Legal)
+			assert.Equal(t, tt.wantTurn, analysis.Turn)
+			assert.Equal(t, tt.wantValue, analysis.Value)
+			if tt.wantErr != "" {
+				assert.Contains(t, w.Body.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore_CreateGameSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		wantCode      int
+		wantSize      int
+		wantWinLength int
+		wantBoardLen  int
+	}{
+		{
+			name:          "classic",
+			input:         `{"board":"---------"}`,
+			wantCode:      201,
+			wantSize:      3,
+			wantWinLength: 3,
+			wantBoardLen:  9,
+		},
+		{
+			name:          "4x4",
+			input:         `{"board":"----------------","size":4}`,
+			wantCode:      201,
+			wantSize:      4,
+			wantWinLength: 4,
+			wantBoardLen:  16,
+		},
+		{
+			name:          "5x5 four in a row",
+			input:         fmt.Sprintf(`{"board":"%s","size":5,"win_length":4}`, strings.Repeat("-", 25)),
+			wantCode:      201,
+			wantSize:      5,
+			wantWinLength: 4,
+			wantBoardLen:  25,
+		},
+		{
+			name:          "gomoku",
+			input:         fmt.Sprintf(`{"board":"%s","size":15}`, strings.Repeat("-", 225)),
+			wantCode:      201,
+			wantSize:      15,
+			wantWinLength: 5,
+			wantBoardLen:  225,
+		},
+		{
+			name:     "board does not match size",
+			input:    `{"board":"---------","size":4}`,
+			wantCode: 400,
+		},
+		{
+			name:     "invalid size",
+			input:    `{"board":"----","size":2}`,
+			wantCode: 400,
+		},
+		{
+			name:     "invalid win length",
+			input:    `{"board":"----------------","size":4,"win_length":6}`,
+			wantCode: 400,
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, w, err := callCreateGame(store.Router, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantSize, game.Size)
+			assert.Equal(t, tt.wantWinLength, game.WinLength)
+			assert.Equal(t, tt.wantBoardLen, len(game.Board))
+		})
+	}
+}
+
+func TestStore_MakeMoveLargeBoard(t *testing.T) {
+	store := NewStore()
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"----------------","size":4}`)
+	url := fmt.Sprintf("/api/v1/games/%s", game.ID.String())
+
+	move := strings.Replace(game.Board, "-", "X", 1)
+
+	tests := []struct {
+		name     string
+		move     string
+		wantCode int
+		wantX    int
+		wantO    int
+	}{
+		{
+			name:     "classic board",
+			move:     `{"board":"---------"}`,
+			wantCode: 400,
+		},
+		{
+			name:     "valid",
+			move:     fmt.Sprintf(`{"board":"%s"}`, move),
+			wantCode: 200,
+			wantX:    1,
+			wantO:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(tt.move))
+
+			store.Router.ServeHTTP(w, req)
+
+			next := &Game{}
+			if err := json.Unmarshal(w.Body.Bytes(), next); err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantX, strings.Count(next.Board, "X"))
+			assert.Equal(t, tt.wantO, strings.Count(next.Board, "O"))
+		})
+	}
+}
+
+func TestStore_Concurrency(t *testing.T) {
+	store := NewStore()
+	const n = 20
+
+	var wg sync.WaitGroup
+	games := make([]*Game, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			games[i], _, _ = callCreateGame(store.Router, `{"board":"X--------","strategy":"random","difficulty":"easy"}`)
+			_, _ = callGetAllGames(store.Router)
+		}(i)
+	}
+	wg.Wait()
+
+	all, _ := callGetAllGames(store.Router)
+	assert.Len(t, all, n)
+
+	// The same move sent many times to one game is accepted exactly once.
+	game := games[0]
+	move := fmt.Sprintf(`{"board":"%s"}`, strings.Replace(game.Board, "-", "X", 1))
+	codes := make(chan int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/api/v1/games/"+game.ID.String(), bytes.NewBufferString(move))
+			store.Router.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	accepted := 0
+	for code := range codes {
+		if code == 200 {
+			accepted++
+		}
+	}
+	assert.Equal(t, 1, accepted)
+
+	// Moves, reads and deletes on different games run side by side.
+	for _, game := range games[1:] {
+		wg.Add(3)
+		go func(game *Game) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			move := fmt.Sprintf(`{"board":"%s"}`, strings.Replace(game.Board, "-", "X", 1))
+			req, _ := http.NewRequest("PUT", "/api/v1/games/"+game.ID.String(), bytes.NewBufferString(move))
+			store.Router.ServeHTTP(w, req)
+			assert.Equal(t, 200, w.Code)
+		}(game)
+		go func(game *Game) {
+			defer wg.Done()
+			_, w, _ := callGetSingleGame(store.Router, game.ID.String())
+			assert.Equal(t, 200, w.Code)
+		}(game)
+		go func(game *Game) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/api/v1/games/"+games[0].ID.String(), nil)
+			store.Router.ServeHTTP(w, req)
+		}(game)
+	}
+	wg.Wait()
+
+	all, _ = callGetAllGames(store.Router)
+	assert.Len(t, all, n-1)
+	assert.Empty(t, store.locks, "locks are dropped once released")
+}
+
+func TestStore_LocksOfUnknownGames(t *testing.T) {
+	store := NewStore()
+	id := uuid.New().String()
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: "DELETE", path: "/api/v1/games/" + id},
+		{method: "PUT", path: "/api/v1/games/" + id, body: `{"board":"X--------"}`},
+		{method: "POST", path: "/api/v1/games/" + id + "/moves", body: `{"cell":0}`},
+		{method: "POST", path: "/api/v1/games/" + id + "/undo"},
+	}
+	for _, r := range requests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(r.method, r.path, bytes.NewBufferString(r.body))
+		store.Router.ServeHTTP(w, req)
+		assert.Equal(t, 404, w.Code)
+	}
+	assert.Empty(t, store.locks)
+}
+
+func TestStore_IfMatch(t *testing.T) {
+	store := NewStore()
+
+	game, w, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	assert.Equal(t, 1, game.Version)
+
+	url := fmt.Sprintf("/api/v1/games/%s", game.ID.String())
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		ifMatch  string
+		wantCode int
+		wantETag string
+	}{
+		{
+			name:     "get",
+			method:   "GET",
+			wantCode: 200,
+			wantETag: `"1"`,
+		},
+		{
+			name:     "move on current version",
+			method:   "PUT",
+			body:     `{"board":"XX--O----"}`,
+			ifMatch:  `"1"`,
+			wantCode: 200,
+			wantETag: `"2"`,
+		},
+		{
+			name:     "move on stale version",
+			method:   "PUT",
+			body:     `{"board":"XXO-O-X--"}`,
+			ifMatch:  `"1"`,
+			wantCode: 412,
+		},
+		{
+			name:     "move without If-Match",
+			method:   "PUT",
+			body:     `{"board":"XXO-O-X--"}`,
+			wantCode: 200,
+			wantETag: `"3"`,
+		},
+		{
+			name:     "delete stale version",
+			method:   "DELETE",
+			ifMatch:  `"2", "1"`,
+			wantCode: 412,
+		},
+		{
+			name:     "delete any version",
+			method:   "DELETE",
+			ifMatch:  `*`,
+			wantCode: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, url, bytes.NewBufferString(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			store.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantETag, w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestStore_MoveHistory(t *testing.T) {
+	store := NewStore()
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	assert.Equal(t, []int{0, 4}, moveCells(game.Moves))
+	assert.Equal(t, []string{ACTOR_CLIENT, ACTOR_SERVER}, moveActors(game.Moves))
+
+	w := httptest.NewRecorder()
+	url := fmt.Sprintf("/api/v1/games/%s", game.ID.String())
+	req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(`{"board":"XX--O----"}`))
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", url+"/moves", nil)
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	moves := []Move{}
+	if err := json.Unmarshal(w.Body.Bytes(), &moves); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{0, 4, 1, 2}, moveCells(moves))
+	assert.Equal(t, []string{ACTOR_CLIENT, ACTOR_SERVER, ACTOR_CLIENT, ACTOR_SERVER}, moveActors(moves))
+	assert.Equal(t, Symbol(SYMBOL_X), moves[2].Symbol)
+	assert.Equal(t, Symbol(SYMBOL_O), moves[3].Symbol)
+	assert.False(t, moves[3].Time.Before(moves[0].Time))
+
+	empty, _, _ := callCreateGame(store.Router, `{"board":"---------"}`)
+	assert.Equal(t, []string{ACTOR_SERVER}, moveActors(empty.Moves))
+}
+
+func moveCells(moves []Move) []int {
+	cells := make([]int, 0, len(moves))
+	for _, move := range moves {
+		cells = append(cells, move.Cell)
+	}
+	return cells
+}
+
+func moveActors(moves []Move) []string {
+	actors := make([]string, 0, len(moves))
+	for _, move := range moves {
+		actors = append(actors, move.Actor)
+	}
+	return actors
+}
+
+func TestStore_UndoMove(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		undos     int
+		wantCode  int
+		wantBoard string
+	}{
+		{
+			name:      "undo first move",
+			input:     `{"board":"X--------"}`,
+			undos:     1,
+			wantCode:  200,
+			wantBoard: "---------",
+		},
+		{
+			name:      "nothing left to undo",
+			input:     `{"board":"X--------"}`,
+			undos:     2,
+			wantCode:  400,
+			wantBoard: "",
+		},
+		{
+			name:      "server opened",
+			input:     `{"board":"---------"}`,
+			undos:     1,
+			wantCode:  400,
+			wantBoard: "",
+		},
+		{
+			name:      "ranked game",
+			input:     `{"board":"X--------","undo_disabled":true}`,
+			undos:     1,
+			wantCode:  403,
+			wantBoard: "",
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, _, _ := callCreateGame(store.Router, tt.input)
+			url := fmt.Sprintf("/api/v1/games/%s/undo", game.ID.String())
+
+			w := httptest.NewRecorder()
+			for i := 0; i < tt.undos; i++ {
+				w = httptest.NewRecorder()
+				req, _ := http.NewRequest("POST", url, nil)
+				store.Router.ServeHTTP(w, req)
+			}
+
+			undone := &Game{}
+			if err := json.Unmarshal(w.Body.Bytes(), undone); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBoard, undone.Board)
+		})
+	}
+}
+
+func TestStore_PlayerVsPlayer(t *testing.T) {
+	store := NewStore()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/games", bytes.NewBufferString(`{"board":"---------","mode":"pvp"}`))
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+
+	created := struct {
+		Game
+		Seats map[string]string `json:"seats"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, MODE_PVP, created.Mode)
+	assert.Equal(t, "---------", created.Board, "the server does not move")
+	assert.Len(t, created.Seats, 2)
+	assert.NotEqual(t, created.Seats["X"], created.Seats["O"])
+
+	_, w, _ = callGetSingleGame(store.Router, created.ID.String())
+	assert.NotContains(t, w.Body.String(), created.Seats["X"], "seats are only shown on creation")
+
+	url := fmt.Sprintf("/api/v1/games/%s", created.ID.String())
+	tests := []struct {
+		name      string
+		seat      string
+		board     string
+		wantCode  int
+		wantBoard string
+	}{
+		{
+			name:     "no seat token",
+			seat:     "",
+			board:    "X--------",
+			wantCode: 403,
+		},
+		{
+			name:     "unknown seat token",
+			seat:     "nope",
+			board:    "X--------",
+			wantCode: 403,
+		},
+		{
+			name:     "O moves out of turn",
+			seat:     "O",
+			board:    "O--------",
+			wantCode: 409,
+		},
+		{
+			name:     "X plays O's mark",
+			seat:     "X",
+			board:    "O--------",
+			wantCode: 400,
+		},
+		{
+			name:      "X moves",
+			seat:      "X",
+			board:     "X--------",
+			wantCode:  200,
+			wantBoard: "X--------",
+		},
+		{
+			name:     "X moves twice",
+			seat:     "X",
+			board:    "X---X----",
+			wantCode: 409,
+		},
+		{
+			name:      "O moves",
+			seat:      "O",
+			board:     "X---O----",
+			wantCode:  200,
+			wantBoard: "X---O----",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(fmt.Sprintf(`{"board":"%s"}`, tt.board)))
+			token := tt.seat
+			if seat, ok := created.Seats[tt.seat]; ok {
+				token = seat
+			}
+			req.Header.Set(SEAT_TOKEN_HEADER, token)
+			store.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == 200 {
+				moved := &Game{}
+				if err := json.Unmarshal(w.Body.Bytes(), moved); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantBoard, moved.Board)
+			}
+		})
+	}
+
+	undo := func(seat string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", url+"/undo", nil)
+		req.Header.Set(SEAT_TOKEN_HEADER, created.Seats[seat])
+		store.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, 409, undo("X"), "only O can take back O's move")
+	assert.Equal(t, 200, undo("O"))
+	game, _, _ := callGetSingleGame(store.Router, created.ID.String())
+	assert.Equal(t, "X--------", game.Board)
+}
+
+func TestStore_PlayMove(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		move        string
+		wantCode    int
+		wantProblem string
+		wantBoard   string
+	}{
+		{
+			name:      "by cell",
+			input:     `{"board":"X--------"}`,
+			move:      `{"cell":1}`,
+			wantCode:  200,
+			wantBoard: "XXO-O----",
+		},
+		{
+			name:      "by row and col",
+			input:     `{"board":"X--------"}`,
+			move:      `{"row":0,"col":1}`,
+			wantCode:  200,
+			wantBoard: "XXO-O----",
+		},
+		{
+			name:        "taken cell",
+			input:       `{"board":"X--------"}`,
+			move:        `{"cell":4}`,
+			wantCode:    400,
+			wantProblem: CODE_CELL_OCCUPIED,
+		},
+		{
+			name:        "off the board",
+			input:       `{"board":"X--------"}`,
+			move:        `{"row":3,"col":0}`,
+			wantCode:    400,
+			wantProblem: CODE_INVALID_CELL,
+		},
+		{
+			name:        "no cell",
+			input:       `{"board":"X--------"}`,
+			move:        `{}`,
+			wantCode:    400,
+			wantProblem: CODE_INVALID_CELL,
+		},
+		{
+			name:        "not json",
+			input:       `{"board":"X--------"}`,
+			move:        `4`,
+			wantCode:    400,
+			wantProblem: CODE_INVALID_JSON,
+		},
+		{
+			name:        "two-player game without seat",
+			input:       `{"board":"---------","mode":"pvp"}`,
+			move:        `{"cell":4}`,
+			wantCode:    403,
+			wantProblem: CODE_INVALID_SEAT,
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, _, _ := callCreateGame(store.Router, tt.input)
+			w := httptest.NewRecorder()
+			url := fmt.Sprintf("/api/v1/games/%s/moves", game.ID.String())
+			req, _ := http.NewRequest("POST", url, bytes.NewBufferString(tt.move))
+			store.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			response := struct {
+				Board string `json:"board"`
+				Code  string `json:"code"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantBoard, response.Board)
+			assert.Equal(t, tt.wantProblem, response.Code)
+		})
+	}
+}
+
+func TestStore_CreateGameSymbols(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantCode    int
+		wantProblem string
+		wantBoard   string
+		wantClient  Symbol
+		wantServer  Symbol
+		wantFirst   Symbol
+	}{
+		{
+			name:       "server opens by default",
+			input:      `{"board":"---------"}`,
+			wantCode:   201,
+			wantBoard:  "O--------",
+			wantClient: SYMBOL_X,
+			wantServer: SYMBOL_O,
+			wantFirst:  SYMBOL_O,
+		},
+		{
+			name:       "client opens on the board",
+			input:      `{"board":"---O-----"}`,
+			wantCode:   201,
+			wantBoard:  "X--O-----",
+			wantClient: SYMBOL_O,
+			wantServer: SYMBOL_X,
+			wantFirst:  SYMBOL_O,
+		},
+		{
+			name:       "client plays O on an empty board",
+			input:      `{"board":"---------","client_symbol":"o"}`,
+			wantCode:   201,
+			wantBoard:  "X--------",
+			wantClient: SYMBOL_O,
+			wantServer: SYMBOL_X,
+			wantFirst:  SYMBOL_X,
+		},
+		{
+			name:       "client moves first on an empty board",
+			input:      `{"board":"---------","client_symbol":"O","first_player":"O"}`,
+			wantCode:   201,
+			wantBoard:  "---------",
+			wantClient: SYMBOL_O,
+			wantServer: SYMBOL_X,
+			wantFirst:  SYMBOL_O,
+		},
+		{
+			name:       "matching board",
+			input:      `{"board":"X--------","client_symbol":"X","first_player":"X"}`,
+			wantCode:   201,
+			wantBoard:  "X---O----",
+			wantClient: SYMBOL_X,
+			wantServer: SYMBOL_O,
+			wantFirst:  SYMBOL_X,
+		},
+		{
+			name:      "two players",
+			input:     `{"board":"---------","mode":"pvp","client_symbol":"X","first_player":"O"}`,
+			wantCode:  201,
+			wantBoard: "---------",
+			wantFirst: SYMBOL_O,
+		},
+		{
+			name:        "client symbol against the board",
+			input:       `{"board":"X--------","client_symbol":"O"}`,
+			wantCode:    400,
+			wantProblem: CODE_SYMBOL_MISMATCH,
+		},
+		{
+			name:        "first player against the board",
+			input:       `{"board":"X--------","first_player":"O"}`,
+			wantCode:    400,
+			wantProblem: CODE_FIRST_PLAYER_MISMATCH,
+		},
+		{
+			name:        "unknown symbol",
+			input:       `{"board":"---------","client_symbol":"Z"}`,
+			wantCode:    400,
+			wantProblem: CODE_INVALID_SYMBOL,
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/games", bytes.NewBufferString(tt.input))
+			store.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			response := struct {
+				Game
+				Code string `json:"code"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantProblem, response.Code)
+			assert.Equal(t, tt.wantBoard, response.Board)
+			assert.Equal(t, tt.wantClient, response.ClientSymbol)
+			assert.Equal(t, tt.wantServer, response.ServerSymbol)
+			assert.Equal(t, tt.wantFirst, response.FirstPlayer)
+		})
+	}
+}
+
+func TestStore_WinningLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		board        string
+		next         string
+		wantStatus   string
+		wantLine     []int
+		wantLastMove Move
+	}{
+		{
+			name:         "client wins",
+			board:        "XX--OO---",
+			next:         "XXX-OO---",
+			wantStatus:   STATUS_X_WON,
+			wantLine:     []int{0, 1, 2},
+			wantLastMove: Move{Cell: 2, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+		},
+		{
+			name:         "server wins",
+			board:        "XX-OO----",
+			next:         "XX-OO---X",
+			wantStatus:   STATUS_O_WON,
+			wantLine:     []int{3, 4, 5},
+			wantLastMove: Move{Cell: 5, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+		},
+		{
+			name:         "game goes on",
+			board:        "X---O----",
+			next:         "XX--O----",
+			wantStatus:   STATUS_RUNNING,
+			wantLine:     nil,
+			wantLastMove: Move{Cell: 2, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+			setBoard(t, store, game.ID, tt.board, STATUS_RUNNING)
+
+			w := httptest.NewRecorder()
+			url := fmt.Sprintf("/api/v1/games/%s", game.ID.String())
+			req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(fmt.Sprintf(`{"board":"%s"}`, tt.next)))
+			store.Router.ServeHTTP(w, req)
+
+			moved := &Game{}
+			if err := json.Unmarshal(w.Body.Bytes(), moved); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantStatus, moved.Status)
+			assert.Equal(t, tt.wantLine, moved.WinningLine)
+			if assert.NotNil(t, moved.LastMove) {
+				moved.LastMove.Time = time.Time{}
+				assert.Equal(t, tt.wantLastMove, *moved.LastMove)
+			}
+		})
+	}
+}
+
+func TestStore_EarlyDraw(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantBoard string
+	}{
+		{
+			name:      "early draw",
+			input:     `{"board":"---------","early_draw":true}`,
+			wantBoard: "XXOOOXXO-",
+		},
+		{
+			name:      "played to the end",
+			input:     `{"board":"---------"}`,
+			wantBoard: "XXOOOXXOO",
+		},
+	}
+
+	store := NewStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, _, _ := callCreateGame(store.Router, tt.input)
+			setBoard(t, store, game.ID, "XXOOO-XO-", STATUS_RUNNING)
+
+			w := httptest.NewRecorder()
+			url := fmt.Sprintf("/api/v1/games/%s/moves", game.ID.String())
+			req, _ := http.NewRequest("POST", url, bytes.NewBufferString(`{"cell":5}`))
+			store.Router.ServeHTTP(w, req)
+
+			moved := &Game{}
+			if err := json.Unmarshal(w.Body.Bytes(), moved); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, STATUS_DRAW, moved.Status)
+			assert.Equal(t, tt.wantBoard, moved.Board)
+		})
+	}
+}
+
+func TestStore_PredictedOutcome(t *testing.T) {
+	store := NewStore()
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------","predict":true}`)
+	assert.Equal(t, STATUS_DRAW, game.PredictedOutcome)
+
+	setBoard(t, store, game.ID, "XO-------", STATUS_RUNNING)
+	w := httptest.NewRecorder()
+	url := fmt.Sprintf("/api/v1/games/%s/moves", game.ID.String())
+	req, _ := http.NewRequest("POST", url, bytes.NewBufferString(`{"cell":4}`))
+	store.Router.ServeHTTP(w, req)
+
+	moved := &Game{}
+	if err := json.Unmarshal(w.Body.Bytes(), moved); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "XO--X---O", moved.Board)
+	assert.Equal(t, STATUS_X_WON, moved.PredictedOutcome)
+
+	unpredicted, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	assert.Empty(t, unpredicted.PredictedOutcome)
+}
+
+func TestStore_Problem(t *testing.T) {
+	store := NewStore()
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+
+	w := httptest.NewRecorder()
+	url := fmt.Sprintf("/api/v1/games/%s", game.ID.String())
+	req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(`{"board":"X---X----"}`))
+	store.Router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, PROBLEM_CONTENT_TYPE, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"detail": "Cell is taken",
+		"code": "CELL_OCCUPIED",
+		"cell": 4
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/games", bytes.NewBufferString(`{"board":`))
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), CODE_INVALID_JSON)
+}
+
+func TestStore_FinishedGame(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{name: "board", method: "PUT", path: "", body: `{"board":"XXXOO-X--"}`, wantCode: 409},
+		{name: "cell", method: "POST", path: "/moves", body: `{"cell":5}`, wantCode: 409},
+		{name: "hint", method: "GET", path: "/hint", body: "", wantCode: 409},
+		{name: "delete", method: "DELETE", path: "", body: "", wantCode: 200},
+	}
+
+	store := NewStore()
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	setBoard(t, store, game.ID, "XXXOO----", STATUS_X_WON)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			url := fmt.Sprintf("/api/v1/games/%s%s", game.ID.String(), tt.path)
+			req, _ := http.NewRequest(tt.method, url, bytes.NewBufferString(tt.body))
+			store.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode != 200 {
+				assert.Contains(t, w.Body.String(), CODE_GAME_FINISHED)
+			}
+		})
+	}
+}
+
+func TestStore_Location(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		headers map[string]string
+		want    string
+	}{
+		{name: "request host", config: DefaultConfig(), want: "http://127.0.0.1:8080"},
+		{
+			name:    "forwarded",
+			config:  DefaultConfig(),
+			headers: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "games.example.com, proxy.local"},
+			want:    "https://games.example.com",
+		},
+		{
+			name:    "base url",
+			config:  Config{BaseURL: "https://example.com/tictactoe/", Strategy: DEFAULT_STRATEGY, MaxSize: MAX_SIZE},
+			headers: map[string]string{"X-Forwarded-Host": "games.example.com"},
+			want:    "https://example.com/tictactoe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(WithConfig(tt.config))
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "http://127.0.0.1:8080/api/v1/games", bytes.NewBufferString(`{"board":"---------"}`))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			store.Router.ServeHTTP(w, req)
+
+			game := &Game{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), game))
+			assert.Equal(t, tt.want+"/api/v1/games/"+game.ID.String(), w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestStore_Limits(t *testing.T) {
+	config := DefaultConfig()
+	config.Strategy = STRATEGY_RANDOM
+	config.MaxSize = 4
+	config.MaxGames = 2
+	config.MaxBodyBytes = 64
+	store := NewStore(WithConfig(config))
+
+	tests := []struct {
+		name     string
+		input    string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "too large board", input: `{"board":"-------------------------","size":5}`, wantCode: 400, wantErr: CODE_INVALID_SIZE},
+		{name: "too large body", input: `{"board":"---------","strategy":"` + strings.Repeat("x", 64) + `"}`, wantCode: 413, wantErr: CODE_BODY_TOO_LARGE},
+		{name: "first", input: `{"board":"---------"}`, wantCode: 201},
+		{name: "second", input: `{"board":"----------------","size":4}`, wantCode: 201},
+		{name: "too many games", input: `{"board":"---------"}`, wantCode: 503, wantErr: CODE_TOO_MANY_GAMES},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, w, err := callCreateGame(store.Router, tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantErr != "" {
+				assert.Contains(t, w.Body.String(), tt.wantErr)
+			} else {
+				assert.Equal(t, STRATEGY_RANDOM, game.Strategy)
+			}
+		})
+	}
+
+	for input, wantCode := range map[string]int{
+		`{"board":"-------------------------","size":5}`: 400,
+		`{"board":"----------------","size":4}`:          200,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/analysis", bytes.NewBufferString(input))
+		store.Router.ServeHTTP(w, req)
+		assert.Equal(t, wantCode, w.Code, input)
+	}
+}
+
+func TestStore_UndoFinishedGame(t *testing.T) {
+	store := NewStore()
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+
+	won, err := store.Games.Get(game.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	won.Board = "XXXOO----"
+	won.Status = STATUS_X_WON
+	won.WinningLine = []int{0, 1, 2}
+	won.Moves = []Move{
+		{Cell: 0, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+		{Cell: 3, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+		{Cell: 1, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+		{Cell: 4, Symbol: SYMBOL_O, Actor: ACTOR_SERVER},
+		{Cell: 2, Symbol: SYMBOL_X, Actor: ACTOR_CLIENT},
+	}
+	if err := store.Games.Update(won); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/games/%s/undo", game.ID.String()), nil)
+	store.Router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	undone := &Game{}
+	if err := json.Unmarshal(w.Body.Bytes(), undone); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "XX-OO----", undone.Board)
+	assert.Equal(t, STATUS_RUNNING, undone.Status)
+	assert.Empty(t, undone.WinningLine)
+	assert.Len(t, undone.Moves, 4)
+}
@@ -0,0 +1,129 @@
+package game
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+const (
+	STRATEGY_RANDOM  = "random"
+	STRATEGY_GREEDY  = "greedy"
+	STRATEGY_MINIMAX = "minimax"
+	DEFAULT_STRATEGY = STRATEGY_MINIMAX
+
+	DIFFICULTY_EASY       = "easy"
+	DIFFICULTY_MEDIUM     = "medium"
+	DIFFICULTY_HARD       = "hard"
+	DIFFICULTY_IMPOSSIBLE = "impossible"
+	DEFAULT_DIFFICULTY    = DIFFICULTY_IMPOSSIBLE
+)
+
+// difficultyLevels maps each difficulty to the percentage of moves for which
+// the server follows its strategy instead of playing a random cell.
+var difficultyLevels = map[string]int{
+	DIFFICULTY_EASY:       25,
+	DIFFICULTY_MEDIUM:     50,
+	DIFFICULTY_HARD:       80,
+	DIFFICULTY_IMPOSSIBLE: 100,
+}
+
+// Strategy chooses the server's next move. NextMove returns the index of an
+// empty cell on board to be marked with symbol, given the rules of the game.
+// Any other answer is replaced by the move of the minimax strategy.
+type Strategy interface {
+	NextMove(board string, symbol byte, rules Rules) int
+}
+
+// StrategyFunc adapts an ordinary function to the Strategy interface.
+type StrategyFunc func(board string, symbol byte, rules Rules) int
+
+func (f StrategyFunc) NextMove(board string, symbol byte, rules Rules) int {
+	return f(board, symbol, rules)
+}
+
+// StrategyConstructor builds a strategy for a single game. It receives the
+// game's random generator, so strategies that use randomness are
+// deterministic when the generator is seeded.
+type StrategyConstructor func(r *rand.Rand) Strategy
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]StrategyConstructor{
+		STRATEGY_RANDOM: func(r *rand.Rand) Strategy {
+			return StrategyFunc(func(board string, _ byte, _ Rules) int {
+				return randomMove(board, r)
+			})
+		},
+		STRATEGY_GREEDY: func(r *rand.Rand) Strategy {
+			return StrategyFunc(func(board string, symbol byte, rules Rules) int {
+				return greedyMove(board, symbol, rules, r)
+			})
+		},
+		STRATEGY_MINIMAX: func(_ *rand.Rand) Strategy {
+			return StrategyFunc(bestMove)
+		},
+	}
+)
+
+// RegisterStrategy makes a strategy available to new games under name,
+// replacing any strategy previously registered with that name.
+func RegisterStrategy(name string, constructor StrategyConstructor) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[name] = constructor
+}
+
+// Strategies returns the names of all registered strategies in sorted order.
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newStrategy(name string, r *rand.Rand) (Strategy, bool) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	constructor, ok := strategies[name]
+	if !ok {
+		return nil, false
+	}
+	return constructor(r), true
+}
+
+// blend follows optimal for rate percent of the moves and plays a random cell
+// for the rest.
+func blend(optimal Strategy, rate int, r *rand.Rand) Strategy {
+	return StrategyFunc(func(board string, symbol byte, rules Rules) int {
+		if r.Intn(100) < rate {
+			return optimal.NextMove(board, symbol, rules)
+		}
+		return randomMove(board, r)
+	})
+}
+
+func randomMove(board string, r *rand.Rand) int {
+	emptyCells := (&Game{Board: board}).findEmptyCells()
+	if len(emptyCells) == 0 {
+		return -1
+	}
+	return emptyCells[r.Intn(len(emptyCells))]
+}
+
+// greedyMove completes a line for symbol if it can, blocks the opponent's
+// line if it has to and otherwise plays a random cell.
+func greedyMove(board string, symbol byte, rules Rules, r *rand.Rand) int {
+	for _, s := range []byte{symbol, opponent(symbol)} {
+		for _, i := range (&Game{Board: board}).findEmptyCells() {
+			if evaluate(replaceAtIndex(board, s, i), rules) == wonStatus(s) {
+				return i
+			}
+		}
+	}
+	return randomMove(board, r)
+}
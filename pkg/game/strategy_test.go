@@ -0,0 +1,168 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategy_NextMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		board    string
+		symbol   byte
+		expected []int
+	}{
+		{
+			name:     "random picks an empty cell",
+			strategy: STRATEGY_RANDOM,
+			board:    "XOXOX-OXO",
+			symbol:   SYMBOL_X,
+			expected: []int{5},
+		},
+		{
+			name:     "greedy wins",
+			strategy: STRATEGY_GREEDY,
+			board:    "OO-XX----",
+			symbol:   SYMBOL_X,
+			expected: []int{5},
+		},
+		{
+			name:     "greedy blocks",
+			strategy: STRATEGY_GREEDY,
+			board:    "OO--X----",
+			symbol:   SYMBOL_X,
+			expected: []int{2},
+		},
+		{
+			name:     "greedy plays anywhere",
+			strategy: STRATEGY_GREEDY,
+			board:    "----X----",
+			symbol:   SYMBOL_O,
+			expected: []int{0, 1, 2, 3, 5, 6, 7, 8},
+		},
+		{
+			name:     "minimax",
+			strategy: STRATEGY_MINIMAX,
+			board:    "X--------",
+			symbol:   SYMBOL_O,
+			expected: []int{4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := newStrategy(tt.strategy, rand.New(rand.NewSource(0)))
+			assert.True(t, ok)
+			assert.Contains(t, tt.expected, s.NextMove(tt.board, tt.symbol, classicRules))
+		})
+	}
+}
+
+func TestStrategy_RegisterStrategy(t *testing.T) {
+	RegisterStrategy("last", func(_ *rand.Rand) Strategy {
+		return StrategyFunc(func(board string, _ byte, _ Rules) int {
+			emptyCells := (&Game{Board: board}).findEmptyCells()
+			return emptyCells[len(emptyCells)-1]
+		})
+	})
+
+	assert.Contains(t, Strategies(), "last")
+
+	g := &Game{
+		Board:        "X--------",
+		Strategy:     "last",
+		ServerSymbol: SYMBOL_O,
+	}
+	assert.True(t, g.setStrategy())
+	g.makeCounterMove()
+	assert.Equal(t, "X-------O", g.Board)
+
+	g.Strategy = "unknown"
+	assert.False(t, g.setStrategy())
+}
+
+func TestStrategy_Misbehaving(t *testing.T) {
+	tests := []struct {
+		name string
+		cell int
+	}{
+		{name: "taken cell", cell: 0},
+		{name: "off the board", cell: 99},
+		{name: "negative", cell: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterStrategy("broken", func(_ *rand.Rand) Strategy {
+				return StrategyFunc(func(_ string, _ byte, _ Rules) int {
+					return tt.cell
+				})
+			})
+
+			store := NewStore()
+			game, w, _ := callCreateGame(store.Router, `{"board":"X--------","strategy":"broken"}`)
+			assert.Equal(t, 201, w.Code)
+			assert.Equal(t, "X---O----", game.Board)
+			assert.Equal(t, []int{0, 4}, moveCells(game.Moves))
+		})
+	}
+}
+
+func TestGame_difficulty(t *testing.T) {
+	tests := []struct {
+		name        string
+		difficulty  string
+		wantOptimal bool
+	}{
+		{
+			name:        "easy",
+			difficulty:  DIFFICULTY_EASY,
+			wantOptimal: false,
+		},
+		{
+			name:        "medium",
+			difficulty:  DIFFICULTY_MEDIUM,
+			wantOptimal: false,
+		},
+		{
+			name:        "hard",
+			difficulty:  DIFFICULTY_HARD,
+			wantOptimal: false,
+		},
+		{
+			name:        "impossible",
+			difficulty:  DIFFICULTY_IMPOSSIBLE,
+			wantOptimal: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			play := func() []string {
+				boards := make([]string, 0)
+				g := &Game{
+					Board:           "X--------",
+					Difficulty:      tt.difficulty,
+					ServerSymbol:    SYMBOL_O,
+					randomGenerator: rand.New(rand.NewSource(42)),
+				}
+				assert.True(t, g.setStrategy())
+				for i := 0; i < 50; i++ {
+					g.Board = "X--------"
+					g.makeCounterMove()
+					boards = append(boards, g.Board)
+				}
+				return boards
+			}
+
+			first := play()
+			assert.Equal(t, first, play(), "seeded games must be deterministic")
+
+			optimal := true
+			for _, board := range first {
+				optimal = optimal && board == "X---O----"
+			}
+			assert.Equal(t, tt.wantOptimal, optimal)
+		})
+	}
+}
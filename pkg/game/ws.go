@@ -0,0 +1,340 @@
+package game
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	WS_CREATE    = "create"
+	WS_JOIN      = "join"
+	WS_SUBSCRIBE = "subscribe"
+	WS_MOVE      = "move"
+	WS_GAME      = "game"
+	WS_DELETED   = "deleted"
+	WS_ERROR     = "error"
+
+	// WS_BUFFER is how many messages may wait to be written to a socket
+	// before the connection stops reading requests.
+	WS_BUFFER = 16
+)
+
+// WSRequest is a message from a WebSocket client.
+//
+//   - create starts Game, like POST /api/v1/games, and subscribes to it.
+//   - join subscribes to GameID and remembers Seat for later moves in it.
+//   - subscribe follows GameID without playing in it.
+//   - move plays Cell in GameID, with Seat or the seat the game was joined
+//     with in two-player games.
+type WSRequest struct {
+	Type   string    `json:"type"`
+	GameID uuid.UUID `json:"game_id,omitempty"`
+	Seat   string    `json:"seat,omitempty"`
+	Cell   *int      `json:"cell,omitempty"`
+	Game   *Game     `json:"game,omitempty"`
+}
+
+// WSResponse is a message to a WebSocket client: a game's state, the deletion
+// of a game it follows, or the failure of one of its requests, with the
+// status, code, reason and cell the REST API would answer with.
+type WSResponse struct {
+	Type    string            `json:"type"`
+	Request string            `json:"request,omitempty"`
+	Game    *Game             `json:"game,omitempty"`
+	Seats   map[string]string `json:"seats,omitempty"`
+	Status  int               `json:"status,omitempty"`
+	Code    string            `json:"code,omitempty"`
+	Reason  string            `json:"reason,omitempty"`
+	Cell    *int              `json:"cell,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{}
+
+// wsConn is a WebSocket client with the games it follows.
+type wsConn struct {
+	store *Store
+	out   chan WSResponse
+	done  chan struct{}
+
+	mu       sync.Mutex
+	versions map[uuid.UUID]int
+	seats    map[uuid.UUID]string
+	cancels  map[uuid.UUID]func()
+}
+
+// ServeWebSocket speaks the WebSocket protocol of WSRequest and WSResponse
+// until the client disconnects or the store shuts down, which closes the
+// connection with the going-away status.
+func (s *Store) ServeWebSocket(c *gin.Context) {
+	if !s.openStream() {
+		abortWithError(c, errShuttingDown)
+		return
+	}
+	defer s.closeStream()
+
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+	if s.maxBodyBytes > 0 {
+		ws.SetReadLimit(s.maxBodyBytes)
+	}
+
+	conn := &wsConn{
+		store:    s,
+		out:      make(chan WSResponse, WS_BUFFER),
+		done:     make(chan struct{}),
+		versions: make(map[uuid.UUID]int),
+		seats:    make(map[uuid.UUID]string),
+		cancels:  make(map[uuid.UUID]func()),
+	}
+	defer conn.close()
+
+	go func() {
+		for {
+			select {
+			case response := <-conn.out:
+				if err := ws.WriteJSON(response); err != nil {
+					ws.Close()
+					return
+				}
+			case <-conn.done:
+				return
+			case <-s.done:
+				message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server is shutting down")
+				_ = ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+				ws.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		request := WSRequest{}
+		if err := json.Unmarshal(message, &request); err != nil {
+			conn.fail(request, newAPIError(400, CODE_INVALID_MESSAGE, "Invalid message"))
+			continue
+		}
+		conn.handle(request)
+	}
+}
+
+func (conn *wsConn) close() {
+	close(conn.done)
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	for id, cancel := range conn.cancels {
+		cancel()
+		delete(conn.cancels, id)
+	}
+}
+
+func (conn *wsConn) send(response WSResponse) {
+	select {
+	case conn.out <- response:
+	case <-conn.done:
+	}
+}
+
+// reply answers request with game.
+func (conn *wsConn) reply(request WSRequest, game *Game, seats map[string]string) {
+	conn.mu.Lock()
+	if conn.versions[game.ID] < game.Version {
+		conn.versions[game.ID] = game.Version
+	}
+	conn.mu.Unlock()
+
+	conn.send(WSResponse{Type: WS_GAME, Request: request.Type, Game: game, Seats: seats})
+}
+
+// update sends a change to a followed game unless the client already has
+// this version of it, for example in the reply to its own move.
+func (conn *wsConn) update(game *Game) {
+	conn.mu.Lock()
+	if conn.versions[game.ID] >= game.Version {
+		conn.mu.Unlock()
+		return
+	}
+	conn.versions[game.ID] = game.Version
+	conn.mu.Unlock()
+
+	conn.send(WSResponse{Type: WS_GAME, Game: game})
+}
+
+func (conn *wsConn) fail(request WSRequest, err *apiError) {
+	problem := err.problem()
+	conn.send(WSResponse{
+		Type:    WS_ERROR,
+		Request: request.Type,
+		Status:  err.status,
+		Code:    problem.Code,
+		Reason:  problem.Detail,
+		Cell:    problem.Cell,
+	})
+}
+
+func (conn *wsConn) handle(request WSRequest) {
+	var err *apiError
+	switch request.Type {
+	case WS_CREATE:
+		err = conn.create(request)
+	case WS_JOIN, WS_SUBSCRIBE:
+		err = conn.subscribe(request)
+	case WS_MOVE:
+		err = conn.move(request)
+	default:
+		err = newAPIError(400, CODE_UNKNOWN_MESSAGE_TYPE, "Unknown message type")
+	}
+	if err != nil {
+		conn.fail(request, err)
+	}
+}
+
+func (conn *wsConn) create(request WSRequest) *apiError {
+	if request.Game == nil || request.Game.Board == "" {
+		return errInvalidLength
+	}
+
+	newGame := request.Game
+	if err := conn.store.startGame(newGame); err != nil {
+		return err
+	}
+
+	conn.follow(newGame.ID)
+	conn.reply(request, newGame, newGame.seats)
+	return nil
+}
+
+// subscribe follows the game of request and answers with its current state.
+// The game is followed before it is read so no change is missed in between,
+// and a request that fails stops following it unless the client already did.
+func (conn *wsConn) subscribe(request WSRequest) *apiError {
+	followed := conn.follow(request.GameID)
+
+	game, err := conn.store.Games.Get(request.GameID)
+	if err != nil {
+		if followed {
+			conn.unfollow(request.GameID)
+		}
+		return conn.store.gameError(request.GameID, err)
+	}
+
+	if request.Type == WS_JOIN && game.Mode == MODE_PVP {
+		if game.seat(request.Seat) == 0 {
+			if followed {
+				conn.unfollow(request.GameID)
+			}
+			return errInvalidSeat
+		}
+		conn.mu.Lock()
+		conn.seats[game.ID] = request.Seat
+		conn.mu.Unlock()
+	}
+
+	conn.reply(request, game, nil)
+	return nil
+}
+
+func (conn *wsConn) move(request WSRequest) *apiError {
+	unlock := conn.store.lockGame(request.GameID)
+	defer unlock()
+
+	game, err := conn.store.Games.Get(request.GameID)
+	if err != nil {
+		return conn.store.gameError(request.GameID, err)
+	}
+	conn.store.attachRandomGenerator(game)
+
+	if game.Status != STATUS_RUNNING {
+		return errGameFinished
+	}
+
+	seat := request.Seat
+	if seat == "" {
+		conn.mu.Lock()
+		seat = conn.seats[game.ID]
+		conn.mu.Unlock()
+	}
+	symbol, apiErr := playerOf(game, seat)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	cell := MoveRequest{Cell: request.Cell}.cell(game.rules())
+	if apiErr := validateCell(game, cell, symbol); apiErr != nil {
+		return apiErr
+	}
+
+	game.move(cell, symbol)
+
+	// Record the new version before the update can reach the client's
+	// subscription, so the client gets the move only once, as the reply.
+	conn.mu.Lock()
+	apiErr = conn.store.updateGame(game)
+	if apiErr == nil {
+		conn.versions[game.ID] = game.Version
+	}
+	conn.mu.Unlock()
+	if apiErr != nil {
+		return apiErr
+	}
+
+	conn.send(WSResponse{Type: WS_GAME, Request: request.Type, Game: game})
+	return nil
+}
+
+// follow forwards the events of the game with the given ID to the client
+// until it disconnects or the game is deleted. It reports false if the
+// client already followed the game.
+func (conn *wsConn) follow(id uuid.UUID) bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if _, ok := conn.cancels[id]; ok {
+		return false
+	}
+
+	events, cancel := conn.store.events.subscribe(id)
+	stop := make(chan struct{})
+	conn.cancels[id] = func() {
+		cancel()
+		close(stop)
+	}
+
+	go func() {
+		for {
+			select {
+			case event := <-events:
+				if event.Type == EVENT_DELETED {
+					conn.unfollow(id)
+					conn.send(WSResponse{Type: WS_DELETED, Game: event.Game})
+					return
+				}
+				conn.update(event.Game)
+			case <-stop:
+				return
+			case <-conn.done:
+				return
+			}
+		}
+	}()
+	return true
+}
+
+func (conn *wsConn) unfollow(id uuid.UUID) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if cancel, ok := conn.cancels[id]; ok {
+		cancel()
+		delete(conn.cancels, id)
+	}
+}
@@ -0,0 +1,236 @@
+package game
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func dialWebSocket(t *testing.T, server *httptest.Server) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws"
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func exchange(t *testing.T, ws *websocket.Conn, request interface{}) WSResponse {
+	if request != nil {
+		if err := ws.WriteJSON(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return receive(t, ws)
+}
+
+func receive(t *testing.T, ws *websocket.Conn) WSResponse {
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	response := WSResponse{}
+	if err := ws.ReadJSON(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestWebSocket_Errors(t *testing.T) {
+	store := NewStore()
+	server := httptest.NewServer(store.Router)
+	defer server.Close()
+	ws := dialWebSocket(t, server)
+
+	created := exchange(t, ws, WSRequest{Type: WS_CREATE, Game: &Game{Board: "X--------"}})
+
+	tests := []struct {
+		name       string
+		request    interface{}
+		wantStatus int
+		wantReason string
+	}{
+		{
+			name:       "not json",
+			request:    "move",
+			wantStatus: 400,
+			wantReason: "Invalid message",
+		},
+		{
+			name:       "unknown type",
+			request:    WSRequest{Type: "resign"},
+			wantStatus: 400,
+			wantReason: "Unknown message type",
+		},
+		{
+			name:       "create without board",
+			request:    WSRequest{Type: WS_CREATE},
+			wantStatus: 400,
+			wantReason: "Invalid input length",
+		},
+		{
+			name:       "create with bad size",
+			request:    WSRequest{Type: WS_CREATE, Game: &Game{Board: "---------", Size: 2}},
+			wantStatus: 400,
+			wantReason: "Invalid board size",
+		},
+		{
+			name:       "unknown game",
+			request:    WSRequest{Type: WS_SUBSCRIBE},
+			wantStatus: 404,
+			wantReason: "Game not found",
+		},
+		{
+			name:       "occupied cell",
+			request:    WSRequest{Type: WS_MOVE, GameID: created.Game.ID, Cell: intPtr(0)},
+			wantStatus: 400,
+			wantReason: "Cell is taken",
+		},
+		{
+			name:       "cell off the board",
+			request:    WSRequest{Type: WS_MOVE, GameID: created.Game.ID, Cell: intPtr(9)},
+			wantStatus: 400,
+			wantReason: "Invalid cell",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := exchange(t, ws, tt.request)
+			assert.Equal(t, WS_ERROR, response.Type)
+			assert.Equal(t, tt.wantStatus, response.Status)
+			assert.Equal(t, tt.wantReason, response.Reason)
+		})
+	}
+}
+
+func TestWebSocket_PlayAgainstServer(t *testing.T) {
+	store := NewStore()
+	server := httptest.NewServer(store.Router)
+	defer server.Close()
+	ws := dialWebSocket(t, server)
+
+	created := exchange(t, ws, WSRequest{Type: WS_CREATE, Game: &Game{Board: "x--------"}})
+	assert.Equal(t, WS_GAME, created.Type)
+	assert.Equal(t, WS_CREATE, created.Request)
+	assert.Equal(t, "X---O----", created.Game.Board)
+
+	moved := exchange(t, ws, WSRequest{Type: WS_MOVE, GameID: created.Game.ID, Cell: intPtr(1)})
+	assert.Equal(t, WS_MOVE, moved.Request)
+	assert.Equal(t, "XXO-O----", moved.Game.Board)
+	assert.Equal(t, 2, moved.Game.Version)
+
+	stored, _ := store.Games.Get(created.Game.ID)
+	assert.Equal(t, moved.Game.Board, stored.Board)
+
+	setBoard(t, store, created.Game.ID, "XXXOO----", STATUS_X_WON)
+	finished := exchange(t, ws, WSRequest{Type: WS_MOVE, GameID: created.Game.ID, Cell: intPtr(5)})
+	assert.Equal(t, 409, finished.Status)
+	assert.Equal(t, CODE_GAME_FINISHED, finished.Code)
+}
+
+func TestWebSocket_PlayerVsPlayer(t *testing.T) {
+	store := NewStore()
+	server := httptest.NewServer(store.Router)
+	defer server.Close()
+	x, o, spectator := dialWebSocket(t, server), dialWebSocket(t, server), dialWebSocket(t, server)
+
+	created := exchange(t, x, WSRequest{Type: WS_CREATE, Game: &Game{Board: "---------", Mode: MODE_PVP}})
+	id := created.Game.ID
+	assert.Len(t, created.Seats, 2)
+
+	joined := exchange(t, o, WSRequest{Type: WS_JOIN, GameID: id, Seat: "wrong"})
+	assert.Equal(t, 403, joined.Status)
+	joined = exchange(t, o, WSRequest{Type: WS_JOIN, GameID: id, Seat: created.Seats["O"]})
+	assert.Equal(t, WS_GAME, joined.Type)
+	watched := exchange(t, spectator, WSRequest{Type: WS_SUBSCRIBE, GameID: id})
+	assert.Equal(t, "---------", watched.Game.Board)
+
+	early := exchange(t, o, WSRequest{Type: WS_MOVE, GameID: id, Cell: intPtr(4)})
+	assert.Equal(t, 409, early.Status)
+
+	moved := exchange(t, x, WSRequest{Type: WS_MOVE, GameID: id, Seat: created.Seats["X"], Cell: intPtr(4)})
+	assert.Equal(t, "----X----", moved.Game.Board)
+	assert.Equal(t, "----X----", receive(t, o).Game.Board)
+	assert.Equal(t, "----X----", receive(t, spectator).Game.Board)
+
+	moved = exchange(t, o, WSRequest{Type: WS_MOVE, GameID: id, Cell: intPtr(0)})
+	assert.Equal(t, "O---X----", moved.Game.Board)
+	assert.Equal(t, "O---X----", receive(t, x).Game.Board)
+	assert.Equal(t, "O---X----", receive(t, spectator).Game.Board)
+}
+
+func TestWebSocket_JoinWithBadSeat(t *testing.T) {
+	store := NewStore()
+	server := httptest.NewServer(store.Router)
+	defer server.Close()
+	x, stranger, spectator := dialWebSocket(t, server), dialWebSocket(t, server), dialWebSocket(t, server)
+
+	created := exchange(t, x, WSRequest{Type: WS_CREATE, Game: &Game{Board: "---------", Mode: MODE_PVP}})
+	id := created.Game.ID
+
+	joined := exchange(t, stranger, WSRequest{Type: WS_JOIN, GameID: id, Seat: "wrong"})
+	assert.Equal(t, 403, joined.Status)
+	watched := exchange(t, spectator, WSRequest{Type: WS_SUBSCRIBE, GameID: id})
+	assert.Equal(t, WS_GAME, watched.Type)
+	joined = exchange(t, spectator, WSRequest{Type: WS_JOIN, GameID: id, Seat: "wrong"})
+	assert.Equal(t, 403, joined.Status)
+
+	moved := exchange(t, x, WSRequest{Type: WS_MOVE, GameID: id, Seat: created.Seats["X"], Cell: intPtr(4)})
+	assert.Equal(t, "----X----", moved.Game.Board)
+	assert.Equal(t, "----X----", receive(t, spectator).Game.Board, "a failed join keeps an earlier subscription")
+
+	next := exchange(t, stranger, WSRequest{Type: "resign"})
+	assert.Equal(t, WS_ERROR, next.Type, "a failed join must not follow the game")
+}
+
+func TestStore_Drain(t *testing.T) {
+	store := NewStore()
+	server := httptest.NewServer(store.Router)
+	defer server.Close()
+
+	game, _, _ := callCreateGame(store.Router, `{"board":"X--------"}`)
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/games/%s/events", server.URL, game.ID.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	readEvent(t, events)
+
+	ws := dialWebSocket(t, server)
+	exchange(t, ws, WSRequest{Type: WS_SUBSCRIBE, GameID: game.ID})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, store.Drain(ctx))
+
+	_, err = io.ReadAll(events)
+	assert.NoError(t, err, "the event stream ends")
+
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+
+	resp, err = http.Get(fmt.Sprintf("%s/api/v1/games/%s/events", server.URL, game.ID.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, 503, resp.StatusCode)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws"
+	_, resp, err = websocket.DefaultDialer.Dial(url, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 503, resp.StatusCode)
+}